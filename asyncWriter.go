@@ -0,0 +1,259 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueueFullPolicy - defines what an AsyncWriter does with a write when its queue is full
+type QueueFullPolicy int
+
+const (
+	// QueueBlock - wait until there's room in the queue.  This is the default
+	QueueBlock QueueFullPolicy = iota
+	// QueueDrop - silently drop the write
+	QueueDrop
+	// QueueDropCount - drop the write and count it.  The count of dropped writes is written to the underlying writer
+	// after the next batch, as an aggregate with an "async-writer-dropped" header that's encoded by the AsyncWriter's
+	// AggregateEncoder (see WithAsyncEncoder())
+	QueueDropCount
+)
+
+// asyncDroppedHeaderKey - the header of the aggregate which reports the dropped writes for QueueDropCount
+const asyncDroppedHeaderKey = "async-writer-dropped"
+
+// ErrAsyncWriterClosed - returned by an AsyncWriter after Close() has been called
+var ErrAsyncWriterClosed = errors.New("async writer is closed")
+
+// AsyncWriter - an io.Writer which queues writes and writes them to the underlying writer in batches from a goroutine,
+// so a slow writer (like a stdout pipe or a log collector) doesn't add latency to requests.  Use it with WithWriter(),
+// and call Close() when shutting down so the queue is drained.  It's an AggregateWriter, so when the underlying writer
+// is one too (like the SyslogWriter) every aggregate is passed to its WriteAggregate() on its own rather than batched
+type AsyncWriter struct {
+	out       io.Writer
+	opts      asyncWriterOptions
+	queue     chan asyncWrite
+	flushes   chan chan struct{}
+	closing   chan struct{} // closed first by Close(), so blocked writes give up
+	stop      chan struct{} // closed by Close() when there are no more writes, so the queue is drained
+	done      chan struct{}
+	closedMU  sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	dropped   int64
+	reported  int64
+}
+
+// NewAsyncWriter - create an AsyncWriter for the underlying writer, and start the goroutine which writes to it
+func NewAsyncWriter(out io.Writer, opt ...AsyncWriterOption) *AsyncWriter {
+	opts := defaultAsyncWriterOptions()
+	for _, o := range opt {
+		o(&opts)
+	}
+	if opts.queueSize < 1 {
+		opts.queueSize = 1
+	}
+	if opts.batchSize < 1 {
+		opts.batchSize = 1
+	}
+	if opts.flushInterval <= 0 {
+		opts.flushInterval = DefaultAsyncFlushInterval
+	}
+	if opts.encoder == nil {
+		opts.encoder = JSONEncoder()
+	}
+	w := &AsyncWriter{
+		out:     out,
+		opts:    opts,
+		queue:   make(chan asyncWrite, opts.queueSize),
+		flushes: make(chan chan struct{}),
+		closing: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write - queue a copy of p, which is written later.  When the queue is full, the QueueFullPolicy decides if it waits
+// or p is dropped (dropped writes still return len(p), since the caller can't do anything about them).  A write that's
+// waiting when Close() is called returns ErrAsyncWriterClosed
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	if err := w.enqueue(asyncWrite{p: append([]byte(nil), p...)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteAggregate - queue a copy of the encoded aggregate, just like Write().  When the underlying writer is an
+// AggregateWriter, the aggregate is written with its WriteAggregate() (and never batched with other writes)
+func (w *AsyncWriter) WriteAggregate(a Aggregate, encoded []byte) error {
+	write := asyncWrite{p: append([]byte(nil), encoded...)}
+	if _, ok := w.out.(AggregateWriter); ok {
+		write.a = &a
+	}
+	return w.enqueue(write)
+}
+
+// asyncWrite - a queued write, with the aggregate when it's for an underlying AggregateWriter
+type asyncWrite struct {
+	p []byte
+	a *Aggregate
+}
+
+// enqueue - queue the write, using the QueueFullPolicy when the queue is full
+func (w *AsyncWriter) enqueue(write asyncWrite) error {
+	w.closedMU.RLock()
+	defer w.closedMU.RUnlock()
+	if w.closed {
+		return ErrAsyncWriterClosed
+	}
+	if w.opts.queueFullPolicy == QueueBlock {
+		select {
+		case w.queue <- write:
+			return nil
+		case <-w.closing:
+			return ErrAsyncWriterClosed
+		}
+	}
+	select {
+	case w.queue <- write:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+	return nil
+}
+
+// Dropped - the number of writes that were dropped because the queue was full
+func (w *AsyncWriter) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Flush - write everything that's queued to the underlying writer, waiting until it's done or ctx is done
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case w.flushes <- ack:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close - stop accepting writes, and drain the queue to the underlying writer, waiting until it's done or ctx is done
+// (the queue is still drained in the background after ctx is done).  It doesn't close the underlying writer
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.closeOnce.Do(func() {
+		// blocked writes give up first, so the lock doesn't wait for them (the underlying writer may be stuck)
+		close(w.closing)
+		w.closedMU.Lock()
+		w.closed = true
+		w.closedMU.Unlock()
+		close(w.stop)
+	})
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run - write the queued writes in batches, until the writer is closed and the queue is drained
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.flushInterval)
+	defer ticker.Stop()
+	var batch bytes.Buffer
+	n := 0
+	add := func(write asyncWrite) {
+		if write.a != nil {
+			// an aggregate keeps its own boundaries, so the batch so far is written first to keep the order
+			w.write(&batch)
+			n = 0
+			if err := w.out.(AggregateWriter).WriteAggregate(*write.a, write.p); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing async aggregate:", err)
+			}
+			return
+		}
+		batch.Write(write.p)
+		if n++; n >= w.opts.batchSize {
+			w.write(&batch)
+			n = 0
+		}
+	}
+	// drain - add everything that's queued right now
+	drain := func() {
+		for {
+			select {
+			case b := <-w.queue:
+				add(b)
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case b := <-w.queue:
+			add(b)
+		case <-ticker.C:
+			w.write(&batch)
+			n = 0
+		case ack := <-w.flushes:
+			drain()
+			w.write(&batch)
+			n = 0
+			close(ack)
+		case <-w.stop:
+			drain()
+			w.write(&batch)
+			return
+		}
+	}
+}
+
+// write - write the batch (and then the count of dropped writes for QueueDropCount) to the underlying writer
+func (w *AsyncWriter) write(batch *bytes.Buffer) {
+	if batch.Len() != 0 {
+		if _, err := w.out.Write(batch.Bytes()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing async log batch:", err)
+		}
+		batch.Reset()
+	}
+	w.writeDropped()
+}
+
+// writeDropped - write the count of the writes dropped since the last time as an aggregate, which is encoded like the
+// rest of the output (QueueDropCount only)
+func (w *AsyncWriter) writeDropped() {
+	if w.opts.queueFullPolicy != QueueDropCount {
+		return
+	}
+	dropped := atomic.LoadInt64(&w.dropped)
+	if dropped == w.reported {
+		return
+	}
+	a := Aggregate{Header: map[string]interface{}{asyncDroppedHeaderKey: dropped - w.reported}}
+	w.reported = dropped
+	encoded, err := w.opts.encoder.Encode(a)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error encoding the async writer's dropped count:", err)
+		return
+	}
+	writeAggregate(w.out, a, string(encoded))
+}
@@ -0,0 +1,69 @@
+package ginlogrus
+
+import "time"
+
+// AsyncWriterOption - define options for NewAsyncWriter()
+type AsyncWriterOption func(*asyncWriterOptions)
+type asyncWriterOptions struct {
+	queueSize       int
+	batchSize       int
+	flushInterval   time.Duration
+	queueFullPolicy QueueFullPolicy
+	encoder         AggregateEncoder
+}
+
+const (
+	// DefaultAsyncQueueSize - the default number of writes an AsyncWriter queues
+	DefaultAsyncQueueSize = 1024
+	// DefaultAsyncBatchSize - the default number of queued writes an AsyncWriter writes at once
+	DefaultAsyncBatchSize = 64
+	// DefaultAsyncFlushInterval - the default for how long an AsyncWriter waits before writing a partial batch
+	DefaultAsyncFlushInterval = 100 * time.Millisecond
+)
+
+func defaultAsyncWriterOptions() asyncWriterOptions {
+	return asyncWriterOptions{
+		queueSize:     DefaultAsyncQueueSize,
+		batchSize:     DefaultAsyncBatchSize,
+		flushInterval: DefaultAsyncFlushInterval,
+		encoder:       JSONEncoder(),
+	}
+}
+
+// WithQueueSize - define an Option func for the number of writes that are queued, the default is DefaultAsyncQueueSize
+func WithQueueSize(n int) AsyncWriterOption {
+	return func(o *asyncWriterOptions) {
+		o.queueSize = n
+	}
+}
+
+// WithBatchSize - define an Option func for the number of queued writes that are written to the underlying writer at
+// once, the default is DefaultAsyncBatchSize
+func WithBatchSize(n int) AsyncWriterOption {
+	return func(o *asyncWriterOptions) {
+		o.batchSize = n
+	}
+}
+
+// WithFlushInterval - define an Option func for how long to wait before writing a partial batch, the default is
+// DefaultAsyncFlushInterval
+func WithFlushInterval(d time.Duration) AsyncWriterOption {
+	return func(o *asyncWriterOptions) {
+		o.flushInterval = d
+	}
+}
+
+// WithQueueFullPolicy - define an Option func for what happens to a write when the queue is full, the default is QueueBlock
+func WithQueueFullPolicy(p QueueFullPolicy) AsyncWriterOption {
+	return func(o *asyncWriterOptions) {
+		o.queueFullPolicy = p
+	}
+}
+
+// WithAsyncEncoder - define an Option func for the AggregateEncoder of the aggregate that reports the dropped writes
+// (QueueDropCount), so use the same one as WithAggregateEncoder().  The default is JSONEncoder()
+func WithAsyncEncoder(e AggregateEncoder) AsyncWriterOption {
+	return func(o *asyncWriterOptions) {
+		o.encoder = e
+	}
+}
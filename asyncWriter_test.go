@@ -0,0 +1,269 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+// gatedWriter - records every write, and waits for the gate (when there is one) before each write
+type gatedWriter struct {
+	mu     sync.Mutex
+	gate   chan struct{}
+	writes []string
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	if g.gate != nil {
+		<-g.gate
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writes = append(g.writes, string(p))
+	return len(p), nil
+}
+
+func (g *gatedWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return strings.Join(g.writes, "")
+}
+
+func (g *gatedWriter) count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.writes)
+}
+
+func TestAsyncWriterBatches(t *testing.T) {
+	is := is.New(t)
+	out := &gatedWriter{}
+	w := NewAsyncWriter(out, WithBatchSize(5), WithFlushInterval(time.Hour))
+	want := ""
+	for i := 0; i < 12; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		want += line
+		_, err := w.Write([]byte(line))
+		is.NoErr(err)
+	}
+	is.NoErr(w.Flush(context.Background()))
+	is.Equal(out.String(), want) // everything is written in order
+	is.Equal(out.count(), 3)     // two full batches and the rest when it's flushed
+
+	is.NoErr(w.Close(context.Background()))
+	_, err := w.Write([]byte("too late\n"))
+	is.Equal(err, ErrAsyncWriterClosed)
+	is.NoErr(w.Flush(context.Background())) // nothing to flush after it's closed
+}
+
+func TestAsyncWriterFlushInterval(t *testing.T) {
+	out := &gatedWriter{}
+	w := NewAsyncWriter(out, WithFlushInterval(5*time.Millisecond))
+	defer w.Close(context.Background())
+	_, _ = w.Write([]byte("hi\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for out.String() != "hi\n" {
+		if time.Now().After(deadline) {
+			t.Fatal("the partial batch wasn't written after the flush interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAsyncWriterQueueFullPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      QueueFullPolicy
+		opt         []AsyncWriterOption
+		wantDropped int64
+		wantOut     string
+	}{
+		{"block", QueueBlock, nil, 0, "1\n2\n3\n"},
+		{"drop", QueueDrop, nil, 1, "1\n2\n"},
+		{"drop-count", QueueDropCount, nil, 1, "1\n{\"async-writer-dropped\":1,\"entries\":[]}\n2\n"},
+		{"drop-count-logfmt", QueueDropCount, []AsyncWriterOption{WithAsyncEncoder(LogfmtEncoder())}, 1, "1\nasync-writer-dropped=1\n2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &gatedWriter{gate: make(chan struct{})}
+			w := NewAsyncWriter(out, append(tt.opt, WithQueueSize(1), WithBatchSize(1), WithFlushInterval(time.Hour),
+				WithQueueFullPolicy(tt.policy))...)
+
+			_, _ = w.Write([]byte("1\n"))
+			// wait until the first write is stuck at the gate, so the queue is empty
+			for len(w.queue) != 0 {
+				time.Sleep(time.Millisecond)
+			}
+			_, _ = w.Write([]byte("2\n")) // fills the queue
+			third := make(chan struct{})
+			go func() {
+				_, _ = w.Write([]byte("3\n"))
+				close(third)
+			}()
+			select {
+			case <-third:
+				if tt.policy == QueueBlock {
+					t.Fatal("Write() didn't block when the queue was full")
+				}
+			case <-time.After(50 * time.Millisecond):
+				if tt.policy != QueueBlock {
+					t.Fatal("Write() blocked when the queue was full")
+				}
+			}
+			close(out.gate)
+			<-third
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := w.Close(ctx); err != nil {
+				t.Fatalf("Close() = %v", err)
+			}
+			if w.Dropped() != tt.wantDropped {
+				t.Errorf("Dropped() = %d, want %d", w.Dropped(), tt.wantDropped)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestAsyncWriterCloseDeadline(t *testing.T) {
+	out := &gatedWriter{gate: make(chan struct{})}
+	w := NewAsyncWriter(out, WithBatchSize(1))
+	_, _ = w.Write([]byte("stuck\n"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := w.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Flush() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := w.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Close() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the queue is still drained in the background
+	close(out.gate)
+	if err := w.Close(context.Background()); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if out.String() != "stuck\n" {
+		t.Errorf("output = %q, want %q", out.String(), "stuck\n")
+	}
+}
+
+func TestAsyncWriterCloseBlockedWrite(t *testing.T) {
+	out := &gatedWriter{gate: make(chan struct{})}
+	w := NewAsyncWriter(out, WithQueueSize(1), WithBatchSize(1), WithQueueFullPolicy(QueueBlock))
+	_, _ = w.Write([]byte("stuck\n"))
+	for len(w.queue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = w.Write([]byte("queued\n")) // fills the queue
+	blocked := make(chan error)
+	go func() {
+		_, err := w.Write([]byte("blocked\n"))
+		blocked <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the underlying writer is stuck, so Close() gives up at the deadline instead of waiting for the blocked write
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	closed := make(chan error)
+	go func() {
+		closed <- w.Close(ctx)
+	}()
+	select {
+	case err := <-closed:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Close() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() deadlocked with a blocked Write()")
+	}
+	if err := <-blocked; err != ErrAsyncWriterClosed {
+		t.Errorf("blocked Write() = %v, want %v", err, ErrAsyncWriterClosed)
+	}
+
+	close(out.gate)
+	if err := w.Close(context.Background()); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if out.String() != "stuck\nqueued\n" {
+		t.Errorf("output = %q, want %q", out.String(), "stuck\nqueued\n")
+	}
+}
+
+func TestAsyncWriterWithMiddleware(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	w := NewAsyncWriter(&out, WithFlushInterval(time.Hour))
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(w)))
+	r.GET("/", func(c *gin.Context) {
+		logger := GetCtxLogger(c)
+		logger.Info("hello world")
+		c.JSON(200, "Hello world!")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	is.NoErr(w.Close(context.Background()))
+	is.True(strings.Contains(out.String(), `"msg":"hello world"`))
+	is.True(strings.Contains(out.String(), `"request-summary-info"`))
+}
+
+// aggregateRecorder - an AggregateWriter which records the writes and the aggregates
+type aggregateRecorder struct {
+	gatedWriter
+	aggregates []Aggregate
+}
+
+func (r *aggregateRecorder) WriteAggregate(a Aggregate, encoded []byte) error {
+	r.mu.Lock()
+	r.aggregates = append(r.aggregates, a)
+	r.mu.Unlock()
+	_, err := r.Write(encoded)
+	return err
+}
+
+func TestAsyncWriterAggregateWriter(t *testing.T) {
+	is := is.New(t)
+	out := &aggregateRecorder{}
+	w := NewAsyncWriter(out, WithBatchSize(10), WithFlushInterval(time.Hour))
+	var _ AggregateWriter = w
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(w)))
+	r.GET("/", func(c *gin.Context) {
+		GetCtxLogger(c).Info("hello world")
+		c.JSON(200, "Hello world!")
+	})
+
+	_, _ = w.Write([]byte("before\n"))
+	for i := 0; i < 3; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	_, _ = w.Write([]byte("after\n"))
+	is.NoErr(w.Close(context.Background()))
+
+	// every aggregate is written on its own (not batched), in order with the other writes
+	is.Equal(len(out.aggregates), 3)
+	is.Equal(out.count(), 5)
+	is.Equal(out.writes[0], "before\n")
+	for i, a := range out.aggregates {
+		is.Equal(a.Summary()["status"], 200)
+		is.True(strings.Contains(out.writes[i+1], `"request-summary-info"`))
+		is.Equal(strings.Count(out.writes[i+1], `"request-summary-info"`), 1)
+	}
+	is.Equal(out.writes[4], "after\n")
+}
@@ -0,0 +1,129 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// captureBodiesKey - where CaptureBodies() flags the route in the gin.Context
+const captureBodiesKey = "capture-bodies"
+
+// DefaultBodyCaptureContentTypes - the content types of the bodies captured by default
+var DefaultBodyCaptureContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"text/",
+}
+
+// CaptureBodies - returns a gin.HandlerFunc to use on a route (after the ginlogrus middleware) to capture its request and
+// response bodies for every request, and not just the ones with an error status.  It's only used with WithBodyCapture()
+// example:
+//
+//	r.POST("/orders", ginlogrus.CaptureBodies(), createOrder)
+func CaptureBodies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(captureBodiesKey, true)
+	}
+}
+
+// bodyCapture - the captured request and response bodies for a request
+type bodyCapture struct {
+	limit        int
+	contentTypes []string
+	request      []byte
+	requestTrunc bool
+	response     *bodyCaptureWriter
+}
+
+// newBodyCapture - read up to limit bytes of the request body (restoring it for the handler) and wrap the
+// gin.ResponseWriter to capture the response body
+func newBodyCapture(c *gin.Context, limit int, contentTypes []string) *bodyCapture {
+	bc := &bodyCapture{limit: limit, contentTypes: contentTypes}
+	if c.Request.Body != nil && bc.capturable(c.Request.Header.Get("Content-Type")) {
+		// read one extra byte to find out if the body is truncated
+		buf, _ := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+		c.Request.Body = &restoredBody{Reader: io.MultiReader(bytes.NewReader(buf), c.Request.Body), Closer: c.Request.Body}
+		if len(buf) > limit {
+			buf, bc.requestTrunc = buf[:limit], true
+		}
+		bc.request = buf
+	}
+	bc.response = &bodyCaptureWriter{ResponseWriter: c.Writer, limit: limit}
+	c.Writer = bc.response
+	return bc
+}
+
+// fields - the logrus.Fields for the captured bodies, when the request has an error status or the route uses CaptureBodies()
+func (bc *bodyCapture) fields(c *gin.Context) logrus.Fields {
+	fields := logrus.Fields{}
+	if _, enabled := c.Get(captureBodiesKey); !enabled && c.Writer.Status() < 400 && len(c.Errors) == 0 {
+		return fields
+	}
+	if bc.request != nil {
+		fields["request-body"] = string(bc.request)
+		if bc.requestTrunc {
+			fields["request-body-truncated"] = true
+		}
+	}
+	if bc.capturable(bc.response.Header().Get("Content-Type")) && bc.response.body.Len() != 0 {
+		fields["response-body"] = bc.response.body.String()
+		if bc.response.truncated {
+			fields["response-body-truncated"] = true
+		}
+	}
+	return fields
+}
+
+// capturable - is the content type one of the captured content types
+func (bc *bodyCapture) capturable(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range bc.contentTypes {
+		if strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoredBody - the request body for the handler: the bytes read for the capture followed by the rest of the body
+type restoredBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyCaptureWriter - a gin.ResponseWriter that captures up to limit bytes of the response body
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	limit     int
+	body      bytes.Buffer
+	truncated bool
+}
+
+// Write - write the response and capture it
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString - write the response and capture it
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture - add b to the captured body, until it's full
+func (w *bodyCaptureWriter) capture(b []byte) {
+	if room := w.limit - w.body.Len(); len(b) > room {
+		b, w.truncated = b[:room], true
+	}
+	w.body.Write(b)
+}
@@ -0,0 +1,113 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+func TestBodyCapture(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		route         []gin.HandlerFunc
+		contentType   string
+		body          string
+		status        int
+		wantRequest   interface{}
+		wantResponse  interface{}
+		wantTruncated bool
+	}{
+		{
+			name:         "error-status",
+			opts:         []Option{WithBodyCapture(1024)},
+			contentType:  "application/json; charset=utf-8",
+			body:         `{"name":"bob"}`,
+			status:       400,
+			wantRequest:  `{"name":"bob"}`,
+			wantResponse: `{"error":"bad request"}`,
+		},
+		{
+			name:        "success",
+			opts:        []Option{WithBodyCapture(1024)},
+			contentType: "application/json",
+			body:        `{"name":"bob"}`,
+			status:      200,
+		},
+		{
+			name:         "success-capture-bodies",
+			opts:         []Option{WithBodyCapture(1024)},
+			route:        []gin.HandlerFunc{CaptureBodies()},
+			contentType:  "application/json",
+			body:         `{"name":"bob"}`,
+			status:       200,
+			wantRequest:  `{"name":"bob"}`,
+			wantResponse: `{"error":"bad request"}`,
+		},
+		{
+			name:          "truncated",
+			opts:          []Option{WithBodyCapture(5)},
+			contentType:   "application/json",
+			body:          `{"name":"bob"}`,
+			status:        500,
+			wantRequest:   `{"nam`,
+			wantResponse:  `{"err`,
+			wantTruncated: true,
+		},
+		{
+			name:         "content-type",
+			opts:         []Option{WithBodyCapture(1024), WithBodyCaptureContentTypes("text/")},
+			contentType:  "application/json",
+			body:         `{"name":"bob"}`,
+			status:       500,
+			wantRequest:  nil,
+			wantResponse: nil,
+		},
+		{
+			name:        "disabled",
+			contentType: "application/json",
+			body:        `{"name":"bob"}`,
+			status:      500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out bytes.Buffer
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(New(append(tt.opts, WithAggregateLogging(true), WithWriter(&out))...))
+			handler := func(c *gin.Context) {
+				// the handler still gets the whole body
+				body, err := io.ReadAll(c.Request.Body)
+				is.NoErr(err)
+				is.Equal(string(body), tt.body)
+				c.JSON(tt.status, gin.H{"error": "bad request"})
+			}
+			r.POST("/", append(tt.route, handler)...)
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			is.Equal(w.Code, tt.status)
+			is.Equal(w.Body.String(), `{"error":"bad request"}`)
+
+			var aggregate struct {
+				Summary map[string]interface{} `json:"request-summary-info"`
+			}
+			is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+			is.Equal(aggregate.Summary["request-body"], tt.wantRequest)
+			is.Equal(aggregate.Summary["response-body"], tt.wantResponse)
+			_, truncated := aggregate.Summary["request-body-truncated"]
+			is.Equal(truncated, tt.wantTruncated)
+			_, truncated = aggregate.Summary["response-body-truncated"]
+			is.Equal(truncated, tt.wantTruncated)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package ginlogrus
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// requestContextKey - the context.Context key the middleware uses to attach the requestContext to c.Request.Context()
+type requestContextKey struct{}
+
+// loggerContextKey - the context.Context key for a *logrus.Entry stored by WithContext() (or SetCtxLogger())
+type loggerContextKey struct{}
+
+// requestContext - everything code that only has c.Request.Context() needs.  It's kept in the context.Context (rather
+// than looked up in the gin.Context by every caller) since the gin.Context is recycled by gin when the request is over,
+// while the context.Context can outlive the request.  The request id (and what depends on it) is resolved the first time
+// it's needed, since the handler or a later middleware (e.g. go-gin-opentracing) may still set it, and release()
+// resolves the rest for good when the request is over
+type requestContext struct {
+	aggregate *logrus.Logger // the request's aggregate logger, or nil when not aggregate logging
+	redactor  *Redactor
+
+	mu        sync.Mutex
+	c         *gin.Context  // the request's gin.Context, until the request is over
+	logger    *logrus.Entry // the request's entry from GetCtxLogger()
+	fields    logrus.Fields // the requestFields() when not aggregate logging
+	requestID string
+	headers   http.Header // the outbound request headers from InjectRequestHeaders()
+}
+
+// withRequestContext - attach a requestContext for the request to the request's context.Context
+func withRequestContext(c *gin.Context) *requestContext {
+	rc := &requestContext{c: c}
+	if log, found := c.Get(aggregateLoggerKey); found {
+		// the aggregate logger doesn't depend on the request id, so it's ready to use from go routines right away
+		rc.aggregate = log.(*logrus.Logger)
+		rc.logger = GetCtxLogger(c)
+	}
+	if r, found := c.Get(redactorKey); found {
+		rc.redactor = r.(*Redactor)
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestContextKey{}, rc))
+	return rc
+}
+
+// requestContextFrom - the requestContext from c.Request.Context(), when the middleware attached one
+func requestContextFrom(ctx context.Context) (*requestContext, bool) {
+	rc, ok := ctx.Value(requestContextKey{}).(*requestContext)
+	return rc, ok
+}
+
+// setRequestID - called by CxtRequestID() once the id is resolved, so go routines get it without the gin.Context
+func (rc *requestContext) setRequestID(requestID string) {
+	rc.mu.Lock()
+	rc.requestID = requestID
+	rc.mu.Unlock()
+}
+
+// getRequestID - the request id, which is resolved with CxtRequestID() when it's needed before the handler did
+func (rc *requestContext) getRequestID() string {
+	rc.mu.Lock()
+	requestID, c := rc.requestID, rc.c
+	rc.mu.Unlock()
+	if len(requestID) == 0 && c != nil {
+		requestID = CxtRequestID(c)
+	}
+	return requestID
+}
+
+// getLogger - the request's entry and the requestFields() (when not aggregate logging)
+func (rc *requestContext) getLogger() (*logrus.Entry, logrus.Fields) {
+	rc.mu.Lock()
+	logger, fields, c := rc.logger, rc.fields, rc.c
+	rc.mu.Unlock()
+	if logger == nil && c != nil {
+		// only when not aggregate logging, since the aggregate logger is set by withRequestContext()
+		logger, fields = GetCtxLogger(c), requestFields(c)
+		rc.mu.Lock()
+		rc.logger, rc.fields = logger, fields
+		rc.mu.Unlock()
+	}
+	if logger == nil {
+		logger = logrus.NewEntry(logrus.StandardLogger())
+	}
+	return logger, fields
+}
+
+// getHeaders - the outbound request headers from InjectRequestHeaders()
+func (rc *requestContext) getHeaders() http.Header {
+	rc.mu.Lock()
+	headers, c := rc.headers, rc.c
+	rc.mu.Unlock()
+	if headers == nil && c != nil {
+		headers = http.Header{}
+		InjectRequestHeaders(c, &http.Request{Header: headers})
+		rc.mu.Lock()
+		rc.headers = headers
+		rc.mu.Unlock()
+	}
+	return headers
+}
+
+// release - resolve everything that's still missing and forget the gin.Context, since gin recycles it once the
+// request is over
+func (rc *requestContext) release() {
+	rc.getRequestID()
+	rc.getLogger()
+	rc.getHeaders()
+	rc.mu.Lock()
+	rc.c = nil
+	rc.mu.Unlock()
+}
+
+// WithContext - return a copy of ctx with the *logrus.Entry, which FromContext() will return going forward.  It's the
+// context.Context version of SetCtxLogger(), so when ctx comes from c.Request.Context() the entry is moved to the
+// request's aggregate logger (when aggregate logging) or gets the request fields
+func WithContext(ctx context.Context, logger *logrus.Entry) context.Context {
+	if c, ok := ctx.(*gin.Context); ok {
+		if log, found := c.Get(aggregateLoggerKey); found {
+			logger = logger.WithFields(logrus.Fields{})
+			logger.Logger = log.(*logrus.Logger)
+		} else {
+			// not aggregate logging, so make sure to add some needed fields
+			logger = redactingEntry(c, logger.WithFields(requestFields(c)))
+		}
+	} else if rc, ok := requestContextFrom(ctx); ok {
+		if rc.aggregate != nil {
+			logger = logger.WithFields(logrus.Fields{})
+			logger.Logger = rc.aggregate
+		} else {
+			_, fields := rc.getLogger()
+			logger = withRedaction(rc.redactor, logger.WithFields(fields))
+		}
+	}
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext - get the *logrus.Entry for the request from the context.Context.  It's the context.Context version
+// of GetCtxLogger(), so it's the entry from WithContext() or SetCtxLogger(), or the request's logger when ctx comes from
+// c.Request.Context() (or is the *gin.Context).  Otherwise it's an entry for logrus.StandardLogger()
+func FromContext(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*logrus.Entry); ok {
+		return logger
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return GetCtxLogger(c)
+	}
+	if rc, ok := requestContextFrom(ctx); ok {
+		logger, _ := rc.getLogger()
+		return logger
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// SetContextLoggerHeader - the context.Context version of SetCtxLoggerHeader(): if aggregate logging, add header info...
+// otherwise just info log the data passed
+func SetContextLoggerHeader(ctx context.Context, name string, data interface{}) {
+	logger := FromContext(ctx)
+	if buff, ok := logger.Logger.Out.(*LogBuffer); ok {
+		buff.StoreHeader(name, data)
+		return
+	}
+	logger.Infof("%s: %v", name, data)
+}
+
+// ContextRequestID - the context.Context version of CxtRequestID(), which returns "" when ctx doesn't come from
+// c.Request.Context()
+func ContextRequestID(ctx context.Context) string {
+	if c, ok := ctx.(*gin.Context); ok {
+		return CxtRequestID(c)
+	}
+	if rc, ok := requestContextFrom(ctx); ok {
+		return rc.getRequestID()
+	}
+	return ""
+}
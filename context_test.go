@@ -0,0 +1,116 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+// repository - a "deep" func that only gets a context.Context
+func repository(ctx context.Context) {
+	ctx = WithContext(ctx, FromContext(ctx).WithField("layer", "repository"))
+	SetContextLoggerHeader(ctx, "tenant", "acme")
+	FromContext(ctx).Info("hi from the repository")
+}
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		name             string
+		aggregateLogging bool
+	}{
+		{"aggregate", true},
+		{"not-aggregate", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out, summary bytes.Buffer
+			logger := logrus.New()
+			logger.Out = &summary
+			logger.Formatter = &logrus.JSONFormatter{}
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(New(WithAggregateLogging(tt.aggregateLogging), WithWriter(&out), WithLogger(logger)))
+			var requestID string
+			r.GET("/", func(c *gin.Context) {
+				requestID = ContextRequestID(c.Request.Context())
+				is.Equal(requestID, CxtRequestID(c))
+				is.Equal(FromContext(c.Request.Context()), GetCtxLogger(c))
+				if !tt.aggregateLogging {
+					// not aggregate logging, so the entries go to the logger from SetCtxLogger()
+					SetCtxLogger(c, logrus.NewEntry(logger))
+				}
+				repository(c.Request.Context())
+				c.JSON(200, "Hello world!")
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			if tt.aggregateLogging {
+				is.True(strings.Contains(out.String(), `"tenant":"acme"`))
+				is.True(strings.Contains(out.String(), `"msg":"hi from the repository"`))
+				is.True(strings.Contains(out.String(), `"layer":"repository"`))
+				return
+			}
+			is.Equal(out.Len(), 0)
+			is.True(strings.Contains(summary.String(), `"msg":"tenant: acme"`))
+			is.True(strings.Contains(summary.String(), `"msg":"hi from the repository"`))
+			is.True(strings.Contains(summary.String(), `"requestID":"`+requestID+`"`))
+		})
+	}
+}
+
+func TestFromContext_NoRequest(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	is.Equal(FromContext(ctx).Logger, logrus.StandardLogger())
+	is.Equal(ContextRequestID(ctx), "")
+
+	logger := logrus.NewEntry(logrus.New()).WithField("job", "nightly")
+	ctx = WithContext(ctx, logger)
+	is.Equal(FromContext(ctx), logger)
+
+	// a NewBuffer() logger still gets the headers
+	buff := NewBuffer(logger)
+	SetContextLoggerHeader(WithContext(context.Background(), logger), "job-id", 42)
+	is.True(strings.Contains(buff.String(), `"job-id":42`))
+}
+
+func TestFromContext_GoRoutines(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(&out)))
+	var ctx context.Context
+	var requestID string
+	r.GET("/", func(c *gin.Context) {
+		ctx, requestID = c.Request.Context(), CxtRequestID(c)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// only the context.Context is used, so this doesn't race with the handler's use of the gin.Context
+			FromContext(ctx).Info("hi from a go routine")
+			is.Equal(ContextRequestID(ctx), requestID)
+		}()
+		for i := 0; i < 100; i++ {
+			c.Set(fmt.Sprintf("key-%d", i), i)
+		}
+		wg.Wait()
+		c.JSON(200, "Hello world!")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	is.True(strings.Contains(out.String(), `"msg":"hi from a go routine"`))
+
+	// the context.Context outlives the request, and gin recycles the gin.Context for the next request
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	is.Equal(ContextRequestID(ctx), requestID)
+}
@@ -0,0 +1,629 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// summaryHeaderKey - the LogBuffer header with the request summary
+const summaryHeaderKey = "request-summary-info"
+
+// Aggregate - a snapshot of a LogBuffer which is encoded by an AggregateEncoder: the headers (like the
+// "request-summary-info"), the entries (with the truncated marker, if entries were dropped), the overflow summary (nil
+// unless entries were dropped or spilled), whether it's a partial aggregate, the banner (empty unless it's added) and
+// the time of the request summary (zero when there isn't one)
+type Aggregate struct {
+	Header      map[string]interface{}
+	Entries     []LogEntry
+	Overflow    *OverflowSummary
+	Partial     bool
+	Banner      string
+	SummaryTime time.Time
+}
+
+// AggregateEncoder - encodes an Aggregate when a LogBuffer is written (e.g. as JSON or logfmt)
+type AggregateEncoder interface {
+	Encode(a Aggregate) ([]byte, error)
+}
+
+// AggregateEncoderFunc - an adapter to allow the use of ordinary functions as an AggregateEncoder
+type AggregateEncoderFunc func(a Aggregate) ([]byte, error)
+
+// Encode - calls f(a)
+func (f AggregateEncoderFunc) Encode(a Aggregate) ([]byte, error) {
+	return f(a)
+}
+
+// AggregateWriter - a writer (like the SyslogWriter) which also gets the Aggregate when it's written, so it can use
+// the request summary.  The middleware and the OverflowSpill policy call WriteAggregate() instead of Write() for them
+type AggregateWriter interface {
+	io.Writer
+	WriteAggregate(a Aggregate, encoded []byte) error
+}
+
+// writeAggregate - write the encoded aggregate to w, using WriteAggregate() when w is an AggregateWriter
+func writeAggregate(w io.Writer, a Aggregate, encoded string) {
+	if aw, ok := w.(AggregateWriter); ok {
+		if err := aw.WriteAggregate(a, []byte(encoded)); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing aggregate:", err)
+		}
+		return
+	}
+	fmt.Fprint(w, encoded)
+}
+
+// Summary - the "request-summary-info" header, or nil when there isn't one
+func (a Aggregate) Summary() logrus.Fields {
+	return asFields(a.Header[summaryHeaderKey])
+}
+
+// Level - the most severe level of the request summary ("level", or Error for a 5xx status) and the entries, which
+// defaults to Info
+func (a Aggregate) Level() logrus.Level {
+	level := a.summaryLevel()
+	for _, e := range a.Entries {
+		if e.Level < level {
+			level = e.Level
+		}
+	}
+	return level
+}
+
+// summaryLevel - the level of just the request summary: its "level" (from WithSummaryLevel()), or Error for a 5xx
+// status, which defaults to Info
+func (a Aggregate) summaryLevel() logrus.Level {
+	summary := a.Summary()
+	if l, ok := summary["level"].(string); ok {
+		if parsed, err := logrus.ParseLevel(l); err == nil {
+			return parsed
+		}
+	} else if status, ok := toInt(summary["status"]); ok && status >= http.StatusInternalServerError {
+		return logrus.ErrorLevel
+	}
+	return logrus.InfoLevel
+}
+
+// Time - the time of the request summary (whatever the format of its "time" field is), or the time of the last entry
+// (or now) when there isn't one
+func (a Aggregate) Time() time.Time {
+	if !a.SummaryTime.IsZero() {
+		return a.SummaryTime
+	}
+	if len(a.Entries) != 0 {
+		return a.Entries[len(a.Entries)-1].Time
+	}
+	return time.Now()
+}
+
+// message - a one line description of the aggregate (e.g. "GET /users/123 200")
+func (a Aggregate) message() string {
+	summary := a.Summary()
+	if summary == nil {
+		return "aggregate"
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v %v %v", valueOr(summary["method"], ""), valueOr(summary["path"], ""), valueOr(summary["status"], "")))
+}
+
+// JSONEncoder - the default AggregateEncoder: one JSON object with the headers at the top level, the "entries" array
+// (encoded the same way as logrus.JSONFormatter), "overflow", "partial" and "banner".  It always produces valid JSON, since
+// any header or field value that can't be encoded (like a channel or NaN) is encoded as a string, and a header with the
+// same name as one of those members is renamed (see headerMembers())
+func JSONEncoder() AggregateEncoder {
+	return AggregateEncoderFunc(func(a Aggregate) ([]byte, error) {
+		var out bytes.Buffer
+		out.WriteString("{")
+		if len(a.Header) != 0 {
+			writeJSONMembers(&out, headerMembers(a.Header))
+			out.WriteString(",")
+		}
+		entries := make([]string, 0, len(a.Entries))
+		for _, e := range a.Entries {
+			entries = appendEntryJSON(entries, e)
+		}
+		out.WriteString("\"entries\":[" + strings.Join(entries, ",") + "]")
+		if a.Overflow != nil {
+			out.WriteString(",\"overflow\":")
+			out.Write(safeJSON(a.Overflow))
+		}
+		if a.Partial {
+			out.WriteString(",\"partial\":true")
+		}
+		if len(a.Banner) != 0 {
+			out.WriteString(",\"banner\":")
+			out.Write(safeJSON(a.Banner))
+		}
+		out.WriteString("}\n")
+		return out.Bytes(), nil
+	})
+}
+
+// jsonAggregateMembers - the members of the JSONEncoder's object which aren't headers
+var jsonAggregateMembers = map[string]bool{"entries": true, "overflow": true, "partial": true, "banner": true}
+
+// headerMembers - the headers as members of the JSONEncoder's object.  A header with the same name as one of the
+// aggregate's own members (e.g. "entries") gets a "header-" prefix (e.g. "header-entries"), so there aren't duplicate
+// keys
+func headerMembers(header map[string]interface{}) map[string]interface{} {
+	collides := false
+	for k := range header {
+		collides = collides || jsonAggregateMembers[k]
+	}
+	if !collides {
+		return header
+	}
+	members := make(map[string]interface{}, len(header))
+	for k, v := range header {
+		if !jsonAggregateMembers[k] {
+			members[k] = v
+		}
+	}
+	for k, v := range header {
+		if !jsonAggregateMembers[k] {
+			continue
+		}
+		renamed := "header-" + k
+		for _, found := members[renamed]; found; _, found = members[renamed] {
+			renamed = "header-" + renamed
+		}
+		members[renamed] = v
+	}
+	return members
+}
+
+// writeJSONMembers - write the members of a JSON object (without the braces) sorted by key, like json.Marshal does
+func writeJSONMembers(out *bytes.Buffer, obj map[string]interface{}) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i != 0 {
+			out.WriteString(",")
+		}
+		out.Write(safeJSON(k))
+		out.WriteString(":")
+		out.Write(safeJSON(obj[k]))
+	}
+}
+
+// safeJSON - v encoded as JSON.  Errors are encoded as their message, and values that can't be encoded are encoded as a
+// string (with fmt's %v)
+func safeJSON(v interface{}) []byte {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return b
+	}
+	b, _ := json.Marshal(fmt.Sprintf("%v", v))
+	return b
+}
+
+// ECSVersion - the Elastic Common Schema version used by the ECSEncoder
+const ECSVersion = "8.11.0"
+
+// ecsSummaryFields - the request summary fields which are mapped to ECS fields
+var ecsSummaryFields = map[string]string{
+	"method":     "http.request.method",
+	"status":     "http.response.status_code",
+	"path":       "url.path",
+	"ip":         "client.ip",
+	"user-agent": "user_agent.original",
+	"trace-id":   "trace.id",
+	"span-id":    "span.id",
+}
+
+// ECSEncoder - an AggregateEncoder for Elastic Common Schema JSON (https://www.elastic.co/guide/en/ecs/current/).  The
+// request summary is mapped to the ECS http, url, client, user_agent, event, trace and span fields, and everything else
+// (the rest of the summary, the other headers and the entries) is under "gin"
+func ECSEncoder() AggregateEncoder {
+	return AggregateEncoderFunc(func(a Aggregate) ([]byte, error) {
+		doc := map[string]interface{}{
+			"@timestamp":  a.Time().Format(time.RFC3339Nano),
+			"log.level":   a.Level().String(),
+			"message":     a.message(),
+			"ecs.version": ECSVersion,
+		}
+		gin := map[string]interface{}{}
+		for k, v := range a.Header {
+			if k != summaryHeaderKey {
+				gin[k] = v
+			}
+		}
+		if summary := a.Summary(); summary != nil {
+			rest := logrus.Fields{}
+			for k, v := range summary {
+				switch ecs, found := ecsSummaryFields[k]; {
+				case found:
+					setNested(doc, ecs, v)
+				case k == "latency-ms":
+					if ms, ok := v.(float64); ok {
+						setNested(doc, "event.duration", int64(ms*float64(time.Millisecond)))
+					}
+				case k == "time" || k == "level":
+				default:
+					rest[k] = v
+				}
+			}
+			gin[summaryHeaderKey] = rest
+		}
+		entries := make([]map[string]interface{}, 0, len(a.Entries))
+		for _, e := range a.Entries {
+			entries = append(entries, entryObject(e, "@timestamp", "log.level", "message", e.Level.String()))
+		}
+		gin["entries"] = entries
+		addOverflow(gin, a)
+		doc["gin"] = gin
+		return marshalLine(doc)
+	})
+}
+
+// gcpSeverities - the Google Cloud Logging severity for each logrus.Level
+var gcpSeverities = map[logrus.Level]string{
+	logrus.PanicLevel: "EMERGENCY",
+	logrus.FatalLevel: "CRITICAL",
+	logrus.ErrorLevel: "ERROR",
+	logrus.WarnLevel:  "WARNING",
+	logrus.InfoLevel:  "INFO",
+	logrus.DebugLevel: "DEBUG",
+	logrus.TraceLevel: "DEBUG",
+}
+
+// GCPEncoder - an AggregateEncoder for Google Cloud Logging's structured JSON
+// (https://cloud.google.com/logging/docs/structured-logging).  The request summary is mapped to "severity", "time",
+// "httpRequest" and the "logging.googleapis.com/trace" fields (which need the projectID), and everything else is
+// added to the jsonPayload: the rest of the summary, the other headers under "headers" (so they can't collide with the
+// special fields) and the entries.  The summary only has the request's path, so it's not used as the
+// httpRequest.requestUrl (which is the whole URL)
+func GCPEncoder(projectID string) AggregateEncoder {
+	return AggregateEncoderFunc(func(a Aggregate) ([]byte, error) {
+		doc := map[string]interface{}{
+			"severity": gcpSeverities[a.Level()],
+			"time":     a.Time().Format(time.RFC3339Nano),
+			"message":  a.message(),
+		}
+		headers := map[string]interface{}{}
+		for k, v := range a.Header {
+			if k != summaryHeaderKey {
+				headers[k] = v
+			}
+		}
+		if len(headers) != 0 {
+			doc["headers"] = headers
+		}
+		if summary := a.Summary(); summary != nil {
+			httpRequest := map[string]interface{}{}
+			rest := logrus.Fields{}
+			for k, v := range summary {
+				switch k {
+				case "method":
+					httpRequest["requestMethod"] = v
+				case "status":
+					httpRequest["status"] = v
+				case "ip":
+					httpRequest["remoteIp"] = v
+				case "user-agent":
+					httpRequest["userAgent"] = v
+				case "latency-ms":
+					if ms, ok := v.(float64); ok {
+						httpRequest["latency"] = strconv.FormatFloat(ms/1000, 'f', -1, 64) + "s"
+					}
+				case "trace-id":
+					trace := fmt.Sprint(v)
+					if len(projectID) != 0 {
+						trace = "projects/" + projectID + "/traces/" + trace
+					}
+					doc["logging.googleapis.com/trace"] = trace
+				case "span-id":
+					doc["logging.googleapis.com/spanId"] = v
+				case "trace-sampled":
+					doc["logging.googleapis.com/trace_sampled"] = v
+				case "time", "level":
+				default:
+					rest[k] = v
+				}
+			}
+			doc["httpRequest"] = httpRequest
+			doc[summaryHeaderKey] = rest
+		}
+		entries := make([]map[string]interface{}, 0, len(a.Entries))
+		for _, e := range a.Entries {
+			entries = append(entries, entryObject(e, "time", "severity", "message", gcpSeverities[e.Level]))
+		}
+		doc["entries"] = entries
+		addOverflow(doc, a)
+		return marshalLine(doc)
+	})
+}
+
+// LogfmtEncoder - an AggregateEncoder for logfmt: one line with the headers (nested keys are joined with "."), and then
+// one line for each entry
+func LogfmtEncoder() AggregateEncoder {
+	return AggregateEncoderFunc(func(a Aggregate) ([]byte, error) {
+		var out bytes.Buffer
+		writeLogfmt(&out, aggregatePairs(a))
+		out.WriteString("\n")
+		for _, e := range a.Entries {
+			pairs := []logfmtPair{
+				{logrus.FieldKeyTime, e.Time.Format(time.RFC3339)},
+				{logrus.FieldKeyLevel, e.Level.String()},
+				{logrus.FieldKeyMsg, e.Message},
+			}
+			writeLogfmt(&out, append(pairs, flatten("", e.Fields)...))
+			out.WriteString("\n")
+		}
+		return out.Bytes(), nil
+	})
+}
+
+// ANSI colours used by the TextEncoder
+const (
+	colorRed    = 31
+	colorGreen  = 32
+	colorYellow = 33
+	colorBlue   = 36
+	colorGray   = 37
+)
+
+// TextEncoder - an AggregateEncoder for reading the aggregate during local development: the request summary on the
+// first line, with the entries as a tree below it.  The levels and status are coloured when colors is true
+func TextEncoder(colors bool) AggregateEncoder {
+	paint := func(color int, s string) string {
+		if !colors {
+			return s
+		}
+		return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, s)
+	}
+	return AggregateEncoderFunc(func(a Aggregate) ([]byte, error) {
+		var out bytes.Buffer
+		pairs := aggregatePairs(a)
+		if summary := a.Summary(); summary != nil {
+			status, _ := toInt(summary["status"])
+			fmt.Fprintf(&out, "%v %v %s", valueOr(summary["method"], "-"), valueOr(summary["path"], "-"), paint(statusColor(status), fmt.Sprint(valueOr(summary["status"], "-"))))
+			if ms, ok := summary["latency-ms"].(float64); ok {
+				fmt.Fprintf(&out, " %v", time.Duration(ms*float64(time.Millisecond)))
+			}
+			shown := map[string]bool{}
+			for _, k := range []string{"method", "path", "status", "latency-ms"} {
+				shown[summaryHeaderKey+"."+k] = true
+			}
+			rest := pairs[:0]
+			for _, p := range pairs {
+				if !shown[p.key] {
+					rest = append(rest, p)
+				}
+			}
+			pairs = rest
+		} else {
+			out.WriteString("aggregate")
+		}
+		if len(pairs) != 0 {
+			out.WriteString(" ")
+			writeLogfmt(&out, pairs)
+		}
+		out.WriteString("\n")
+		for i, e := range a.Entries {
+			branch := "├─"
+			if i == len(a.Entries)-1 {
+				branch = "└─"
+			}
+			level := strings.ToUpper(e.Level.String())
+			if len(level) > 4 {
+				level = level[:4]
+			}
+			fmt.Fprintf(&out, "%s %s %s %s", branch, e.Time.Format("15:04:05.000"), paint(levelColor(e.Level), level), e.Message)
+			if fields := flatten("", e.Fields); len(fields) != 0 {
+				out.WriteString(" ")
+				writeLogfmt(&out, fields)
+			}
+			out.WriteString("\n")
+		}
+		return out.Bytes(), nil
+	})
+}
+
+// statusColor - the colour for an HTTP status
+func statusColor(status int) int {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return colorRed
+	case status >= http.StatusBadRequest:
+		return colorYellow
+	case status >= http.StatusMultipleChoices:
+		return colorBlue
+	}
+	return colorGreen
+}
+
+// levelColor - the colour for a level, the same as logrus.TextFormatter uses
+func levelColor(l logrus.Level) int {
+	switch l {
+	case logrus.DebugLevel, logrus.TraceLevel:
+		return colorGray
+	case logrus.WarnLevel:
+		return colorYellow
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		return colorRed
+	}
+	return colorBlue
+}
+
+// logfmtPair - a key and its value encoded as a string
+type logfmtPair struct {
+	key   string
+	value string
+}
+
+// aggregatePairs - the headers, overflow, partial and banner of the aggregate as sorted logfmt pairs
+func aggregatePairs(a Aggregate) []logfmtPair {
+	pairs := flatten("", a.Header)
+	if a.Overflow != nil {
+		pairs = append(pairs,
+			logfmtPair{"overflow.policy", a.Overflow.Policy},
+			logfmtPair{"overflow.dropped-entries", strconv.Itoa(a.Overflow.DroppedEntries)},
+			logfmtPair{"overflow.dropped-bytes", strconv.Itoa(a.Overflow.DroppedBytes)},
+			logfmtPair{"overflow.spills", strconv.Itoa(a.Overflow.Spills)})
+	}
+	if a.Partial {
+		pairs = append(pairs, logfmtPair{"partial", "true"})
+	}
+	if len(a.Banner) != 0 {
+		pairs = append(pairs, logfmtPair{"banner", a.Banner})
+	}
+	return pairs
+}
+
+// flatten - the fields as logfmt pairs sorted by key, with the keys of nested objects joined to their parent's with "."
+func flatten(prefix string, fields map[string]interface{}) []logfmtPair {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]logfmtPair, 0, len(fields))
+	for _, k := range keys {
+		key := k
+		if len(prefix) != 0 {
+			key = prefix + "." + k
+		}
+		switch v := fields[k].(type) {
+		case logrus.Fields, map[string]interface{}, map[string]string, map[string]int:
+			pairs = append(pairs, flatten(key, asFields(v))...)
+		case error:
+			pairs = append(pairs, logfmtPair{key, v.Error()})
+		default:
+			pairs = append(pairs, logfmtPair{key, fmt.Sprint(v)})
+		}
+	}
+	return pairs
+}
+
+// writeLogfmt - write the pairs as key=value separated by spaces, quoting values when they need it
+func writeLogfmt(out *bytes.Buffer, pairs []logfmtPair) {
+	for i, p := range pairs {
+		if i != 0 {
+			out.WriteString(" ")
+		}
+		out.WriteString(p.key)
+		out.WriteString("=")
+		if needsQuoting(p.value) {
+			out.WriteString(strconv.Quote(p.value))
+		} else {
+			out.WriteString(p.value)
+		}
+	}
+}
+
+// needsQuoting - does a logfmt value need to be quoted
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// asFields - a nested object as logrus.Fields (nil when v isn't an object)
+func asFields(v interface{}) logrus.Fields {
+	switch m := v.(type) {
+	case logrus.Fields:
+		return m
+	case map[string]interface{}:
+		return m
+	case map[string]string:
+		fields := make(logrus.Fields, len(m))
+		for k, v := range m {
+			fields[k] = v
+		}
+		return fields
+	case map[string]int:
+		fields := make(logrus.Fields, len(m))
+		for k, v := range m {
+			fields[k] = v
+		}
+		return fields
+	}
+	return nil
+}
+
+// entryObject - an entry as a JSON object, using the encoder's keys for the time, level and message
+func entryObject(e LogEntry, timeKey, levelKey, msgKey, level string) map[string]interface{} {
+	obj := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		obj[k] = v
+	}
+	obj[timeKey] = e.Time.Format(time.RFC3339Nano)
+	obj[levelKey] = level
+	obj[msgKey] = e.Message
+	return obj
+}
+
+// addOverflow - add the overflow summary and partial flag to the object
+func addOverflow(obj map[string]interface{}, a Aggregate) {
+	if a.Overflow != nil {
+		obj["overflow"] = a.Overflow
+	}
+	if a.Partial {
+		obj["partial"] = true
+	}
+}
+
+// setNested - set a dotted key (e.g. "http.request.method") as nested objects
+func setNested(obj map[string]interface{}, key string, v interface{}) {
+	parts := strings.Split(key, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := obj[p].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			obj[p] = next
+		}
+		obj = next
+	}
+	obj[parts[len(parts)-1]] = v
+}
+
+// marshalLine - the object as a line of JSON
+func marshalLine(obj interface{}) ([]byte, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// toInt - an integer from a field, which may have been decoded from JSON as a float64
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// valueOr - v or def when v is nil
+func valueOr(v interface{}, def interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	return v
+}
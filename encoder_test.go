@@ -0,0 +1,248 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+// testAggregate - an aggregate for a failed request with two entries
+func testAggregate() Aggregate {
+	t := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Aggregate{
+		Header: map[string]interface{}{
+			"request-summary-info": logrus.Fields{
+				"method":        "GET",
+				"path":          "/users/1",
+				"status":        500,
+				"latency-ms":    1.5,
+				"ip":            "192.0.2.1",
+				"user-agent":    "curl/7.64.1",
+				"time":          "2020-01-01T00:00:01Z",
+				"requestID":     "abc",
+				"trace-id":      "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span-id":       "00f067aa0ba902b7",
+				"trace-sampled": true,
+			},
+			"tenant": "acme",
+		},
+		Entries: []LogEntry{
+			{Time: t, Level: logrus.InfoLevel, Message: "hello world", Fields: logrus.Fields{"requestID": "abc"}},
+			{Time: t.Add(time.Millisecond), Level: logrus.WarnLevel, Message: "careful", Fields: logrus.Fields{"error": errors.New("boom")}},
+		},
+		SummaryTime: t.Add(time.Second),
+	}
+}
+
+func TestAggregateEncoders(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoder AggregateEncoder
+		want    string
+	}{
+		{
+			name:    "json",
+			encoder: JSONEncoder(),
+			want: `{"request-summary-info":{"ip":"192.0.2.1","latency-ms":1.5,"method":"GET","path":"/users/1","requestID":"abc","span-id":"00f067aa0ba902b7","status":500,"time":"2020-01-01T00:00:01Z","trace-id":"4bf92f3577b34da6a3ce929d0e0e4736","trace-sampled":true,"user-agent":"curl/7.64.1"},"tenant":"acme","entries":[` +
+				`{"level":"info","msg":"hello world","requestID":"abc","time":"2020-01-01T00:00:00Z"},{"error":"boom","level":"warning","msg":"careful","time":"2020-01-01T00:00:00Z"}]}` + "\n",
+		},
+		{
+			name:    "ecs",
+			encoder: ECSEncoder(),
+			want: `{"@timestamp":"2020-01-01T00:00:01Z","client":{"ip":"192.0.2.1"},"ecs.version":"8.11.0","event":{"duration":1500000},` +
+				`"gin":{"entries":[{"@timestamp":"2020-01-01T00:00:00Z","log.level":"info","message":"hello world","requestID":"abc"},{"@timestamp":"2020-01-01T00:00:00.001Z","error":"boom","log.level":"warning","message":"careful"}],` +
+				`"request-summary-info":{"requestID":"abc","trace-sampled":true},"tenant":"acme"},` +
+				`"http":{"request":{"method":"GET"},"response":{"status_code":500}},"log.level":"error","message":"GET /users/1 500",` +
+				`"span":{"id":"00f067aa0ba902b7"},"trace":{"id":"4bf92f3577b34da6a3ce929d0e0e4736"},"url":{"path":"/users/1"},"user_agent":{"original":"curl/7.64.1"}}` + "\n",
+		},
+		{
+			name:    "gcp",
+			encoder: GCPEncoder("my-project"),
+			want: `{"entries":[{"message":"hello world","requestID":"abc","severity":"INFO","time":"2020-01-01T00:00:00Z"},{"error":"boom","message":"careful","severity":"WARNING","time":"2020-01-01T00:00:00.001Z"}],` +
+				`"headers":{"tenant":"acme"},"httpRequest":{"latency":"0.0015s","remoteIp":"192.0.2.1","requestMethod":"GET","status":500,"userAgent":"curl/7.64.1"},` +
+				`"logging.googleapis.com/spanId":"00f067aa0ba902b7","logging.googleapis.com/trace":"projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736","logging.googleapis.com/trace_sampled":true,` +
+				`"message":"GET /users/1 500","request-summary-info":{"path":"/users/1","requestID":"abc"},"severity":"ERROR","time":"2020-01-01T00:00:01Z"}` + "\n",
+		},
+		{
+			name:    "logfmt",
+			encoder: LogfmtEncoder(),
+			want: `request-summary-info.ip=192.0.2.1 request-summary-info.latency-ms=1.5 request-summary-info.method=GET request-summary-info.path=/users/1 request-summary-info.requestID=abc request-summary-info.span-id=00f067aa0ba902b7 request-summary-info.status=500 request-summary-info.time=2020-01-01T00:00:01Z request-summary-info.trace-id=4bf92f3577b34da6a3ce929d0e0e4736 request-summary-info.trace-sampled=true request-summary-info.user-agent=curl/7.64.1 tenant=acme` + "\n" +
+				`time=2020-01-01T00:00:00Z level=info msg="hello world" requestID=abc` + "\n" +
+				`time=2020-01-01T00:00:00Z level=warning msg=careful error=boom` + "\n",
+		},
+		{
+			name:    "text",
+			encoder: TextEncoder(false),
+			want: `GET /users/1 500 1.5ms request-summary-info.ip=192.0.2.1 request-summary-info.requestID=abc request-summary-info.span-id=00f067aa0ba902b7 request-summary-info.time=2020-01-01T00:00:01Z request-summary-info.trace-id=4bf92f3577b34da6a3ce929d0e0e4736 request-summary-info.trace-sampled=true request-summary-info.user-agent=curl/7.64.1 tenant=acme` + "\n" +
+				"├─ 00:00:00.000 INFO hello world requestID=abc\n" +
+				"└─ 00:00:00.001 WARN careful error=boom\n",
+		},
+		{
+			name:    "text-colors",
+			encoder: TextEncoder(true),
+			want: "GET /users/1 \x1b[31m500\x1b[0m 1.5ms request-summary-info.ip=192.0.2.1 request-summary-info.requestID=abc request-summary-info.span-id=00f067aa0ba902b7 request-summary-info.time=2020-01-01T00:00:01Z request-summary-info.trace-id=4bf92f3577b34da6a3ce929d0e0e4736 request-summary-info.trace-sampled=true request-summary-info.user-agent=curl/7.64.1 tenant=acme\n" +
+				"├─ 00:00:00.000 \x1b[36mINFO\x1b[0m hello world requestID=abc\n" +
+				"└─ 00:00:00.001 \x1b[33mWARN\x1b[0m careful error=boom\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.encoder.Encode(testAggregate())
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateEncodersReservedHeaders(t *testing.T) {
+	a := Aggregate{
+		Header: map[string]interface{}{"entries": "h1", "banner": "h2", "header-banner": "h3", "severity": "h4",
+			"message": "h5"},
+		Entries: []LogEntry{{Level: logrus.InfoLevel, Message: "hi"}},
+		Partial: true,
+		Banner:  "my banner",
+	}
+	tests := []struct {
+		name    string
+		encoder AggregateEncoder
+		keys    int // how many "entries" keys there are
+		want    map[string]interface{}
+	}{
+		{"json", JSONEncoder(), 1, map[string]interface{}{"header-entries": "h1", "header-header-banner": "h2", "header-banner": "h3",
+			"severity": "h4", "message": "h5", "partial": true, "banner": "my banner"}},
+		{"gcp", GCPEncoder(""), 2, map[string]interface{}{"severity": "INFO", "message": "aggregate",
+			"headers": map[string]interface{}{"entries": "h1", "banner": "h2", "header-banner": "h3", "severity": "h4", "message": "h5"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.encoder.Encode(a)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			// a duplicate key would be decoded without an error, so they're counted
+			if n := strings.Count(string(got), `"entries":`); n != tt.keys {
+				t.Errorf("Encode() = %s, has %d entries keys, want %d", got, n, tt.keys)
+			}
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("Encode() = %s, error = %v", got, err)
+			}
+			for k, want := range tt.want {
+				if !reflect.DeepEqual(decoded[k], want) {
+					t.Errorf("Encode()[%s] = %v, want %v", k, decoded[k], want)
+				}
+			}
+			if entries, ok := decoded["entries"].([]interface{}); !ok || len(entries) != 1 {
+				t.Errorf("Encode() entries = %v", decoded["entries"])
+			}
+		})
+	}
+}
+
+func TestAggregateLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary logrus.Fields
+		entries []logrus.Level
+		want    logrus.Level
+	}{
+		{"no-summary", nil, nil, logrus.InfoLevel},
+		{"ok", logrus.Fields{"status": 200}, []logrus.Level{logrus.DebugLevel}, logrus.InfoLevel},
+		{"5xx", logrus.Fields{"status": 503}, nil, logrus.ErrorLevel},
+		{"5xx-decoded", logrus.Fields{"status": float64(503)}, nil, logrus.ErrorLevel},
+		{"summary-level", logrus.Fields{"status": 503, "level": "warning"}, nil, logrus.WarnLevel},
+		{"entry-level", logrus.Fields{"status": 200}, []logrus.Level{logrus.InfoLevel, logrus.ErrorLevel}, logrus.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Aggregate{}
+			if tt.summary != nil {
+				a.Header = map[string]interface{}{"request-summary-info": tt.summary}
+			}
+			for _, l := range tt.entries {
+				a.Entries = append(a.Entries, LogEntry{Level: l})
+			}
+			if got := a.Level(); got != tt.want {
+				t.Errorf("Aggregate.Level() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregateTime(t *testing.T) {
+	is := is.New(t)
+	out := &aggregateRecorder{}
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	// a summary time that can't be parsed back into the request's time
+	r.Use(New(WithAggregateLogging(true), WithWriter(out), WithTimeFormat(time.Kitchen), WithEmptyAggregateEntries(true)))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(200, "Hello world!")
+	})
+
+	before := time.Now()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	is.Equal(len(out.aggregates), 1)
+	a := out.aggregates[0]
+	is.True(!a.SummaryTime.IsZero())
+	is.True(!a.SummaryTime.Before(before))
+	is.Equal(a.Summary()["time"], a.SummaryTime.Format(time.Kitchen))
+	is.Equal(a.Time(), a.SummaryTime)
+
+	// without a summary time, it's the time of the last entry
+	entryTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a = Aggregate{Header: map[string]interface{}{"request-summary-info": logrus.Fields{"time": "2021-01-01T00:00:00Z"}},
+		Entries: []LogEntry{{Time: entryTime}}}
+	is.Equal(a.Time(), entryTime)
+}
+
+func TestLogfmtQuoting(t *testing.T) {
+	var out bytes.Buffer
+	writeLogfmt(&out, []logfmtPair{{"a", ""}, {"b", "x y"}, {"c", "k=v"}, {"d", `say "hi"`}, {"e", "plain"}})
+	if want := `a="" b="x y" c="k=v" d="say \"hi\"" e=plain`; out.String() != want {
+		t.Errorf("writeLogfmt() = %s, want %s", out.String(), want)
+	}
+}
+
+func TestWithAggregateEncoder(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(&out), WithAggregateEncoder(LogfmtEncoder())))
+	r.GET("/", func(c *gin.Context) {
+		logger := GetCtxLogger(c)
+		logger.Info("hello world")
+		c.JSON(200, "Hello world!")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	is.Equal(len(lines), 2) // the summary and the entry
+	is.True(strings.Contains(lines[0], "request-summary-info.status=200"))
+	is.True(strings.Contains(lines[1], `msg="hello world"`))
+
+	// the buffer's Aggregate() is what the encoder gets
+	b := NewLogBuffer(WithHeader("k", "v"), WithBanner(true), WithCustomBanner("my banner"))
+	_, _ = b.Write([]byte(`{"msg":"hi"}`))
+	a := b.Aggregate()
+	is.Equal(a.Header["k"], "v")
+	is.Equal(len(a.Entries), 1)
+	is.Equal(a.Banner, "my banner")
+	var decoded map[string]interface{}
+	is.NoErr(json.Unmarshal([]byte(b.String()), &decoded))
+	is.Equal(decoded["banner"], "my banner")
+}
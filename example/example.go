@@ -9,7 +9,7 @@ import (
 
 	"github.com/opentracing/opentracing-go/ext"
 
-	ginlogrus "github.com/Bose/go-gin-logrus"
+	ginlogrus "github.com/Bose/go-gin-logrus/v2"
 	ginopentracing "github.com/Bose/go-gin-opentracing"
 	"github.com/gin-gonic/gin"
 	opentracing "github.com/opentracing/opentracing-go"
@@ -45,13 +45,14 @@ func main() {
 	r.Use(gin.Recovery()) // add Recovery middleware
 	useBanner := true
 	useUTC := true
-	r.Use(ginlogrus.WithTracing(logrus.StandardLogger(),
-		useBanner,
-		time.RFC3339,
-		useUTC,
-		"requestID",
-		[]byte("uber-trace-id"), // where jaeger might have put the trace id
-		[]byte("RequestID"),     // where the trace ID might already be populated in the headers
+	r.Use(ginlogrus.New(
+		ginlogrus.WithLogger(logrus.StandardLogger()),
+		ginlogrus.WithLogBanner(useBanner),
+		ginlogrus.WithTimeFormat(time.RFC3339),
+		ginlogrus.WithUTC(useUTC),
+		ginlogrus.WithTraceIDFieldName("requestID"),
+		ginlogrus.WithTraceIDHeader("uber-trace-id"),   // where jaeger might have put the trace id
+		ginlogrus.WithContextTraceIDField("RequestID"), // where the trace ID might already be populated in the headers
 		ginlogrus.WithAggregateLogging(true)))
 
 	r.GET("/", func(c *gin.Context) {
@@ -78,7 +79,7 @@ func main() {
 			buff := ginlogrus.NewBuffer(logger)
 			time.Sleep(1 * time.Second)
 			logger.Info("Hi from a goroutine completing after the request")
-			fmt.Printf(buff.String())
+			fmt.Print(buff.String())
 		}()
 		c.JSON(200, "Hello world!")
 	})
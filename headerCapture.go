@@ -0,0 +1,81 @@
+package ginlogrus
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RedactedValue - replaces the value of a redacted header
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders - the headers that are always redacted, even when they're in a header allowlist.  Headers
+// with "api-key", "apikey", "api_key", "token" or "secret" in their name are redacted as well
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"Api-Key",
+}
+
+// secretHeaderParts - any header with one of these in its (lowercase) name is redacted
+var secretHeaderParts = []string{"api-key", "apikey", "api_key", "token", "secret"}
+
+// headerCapture - captures the allowlisted request and response headers, redacting secrets
+type headerCapture struct {
+	request  []string
+	response []string
+	redacted map[string]bool
+}
+
+// newHeaderCapture - create a headerCapture for the allowlists, with the redacted headers added to DefaultRedactedHeaders
+func newHeaderCapture(request, response, redacted []string) *headerCapture {
+	hc := &headerCapture{request: request, response: response, redacted: map[string]bool{}}
+	for _, h := range append(append([]string{}, DefaultRedactedHeaders...), redacted...) {
+		hc.redacted[http.CanonicalHeaderKey(h)] = true
+	}
+	return hc
+}
+
+// requestHeaders - the allowlisted request headers found in h
+func (hc *headerCapture) requestHeaders(h http.Header) map[string]string {
+	return hc.capture(h, hc.request)
+}
+
+// responseHeaders - the allowlisted response headers found in h
+func (hc *headerCapture) responseHeaders(h http.Header) map[string]string {
+	return hc.capture(h, hc.response)
+}
+
+// capture - the allowlisted headers found in h (multiple values are joined with ", "), with secrets redacted
+func (hc *headerCapture) capture(h http.Header, allowlist []string) map[string]string {
+	captured := map[string]string{}
+	for _, name := range allowlist {
+		name = http.CanonicalHeaderKey(name)
+		values, found := h[name]
+		if !found {
+			continue
+		}
+		if hc.isRedacted(name) {
+			captured[name] = RedactedValue
+			continue
+		}
+		captured[name] = strings.Join(values, ", ")
+	}
+	return captured
+}
+
+// isRedacted - is the (canonical) header a secret
+func (hc *headerCapture) isRedacted(name string) bool {
+	if hc.redacted[name] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, part := range secretHeaderParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
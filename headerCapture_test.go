@@ -0,0 +1,98 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+func TestHeaderCapture(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	h.Add("X-Forwarded-For", "10.0.0.1")
+	h.Add("X-Forwarded-For", "10.0.0.2")
+	h.Set("Authorization", "Bearer abc")
+	h.Set("Cookie", "session=abc")
+	h.Set("X-Acme-Api-Key", "abc")
+	h.Set("X-Internal", "abc")
+	h.Set("X-Client-Version", "1.2.3")
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		redacted  []string
+		want      map[string]string
+	}{
+		{
+			name:      "allowlist",
+			allowlist: []string{"content-type", "X-Forwarded-For", "X-Client-Version", "X-Missing"},
+			want: map[string]string{
+				"Content-Type":     "application/json",
+				"X-Forwarded-For":  "10.0.0.1, 10.0.0.2",
+				"X-Client-Version": "1.2.3",
+			},
+		},
+		{
+			name:      "secrets-always-redacted",
+			allowlist: []string{"Authorization", "cookie", "X-Acme-Api-Key"},
+			want: map[string]string{
+				"Authorization":  RedactedValue,
+				"Cookie":         RedactedValue,
+				"X-Acme-Api-Key": RedactedValue,
+			},
+		},
+		{
+			name:      "denylist",
+			allowlist: []string{"X-Internal", "X-Client-Version"},
+			redacted:  []string{"x-internal"},
+			want: map[string]string{
+				"X-Internal":       RedactedValue,
+				"X-Client-Version": "1.2.3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := newHeaderCapture(tt.allowlist, nil, tt.redacted)
+			if got := hc.requestHeaders(h); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requestHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRequestHeaders(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithWriter(&out),
+		WithRequestHeaders("X-Client-Version", "Authorization"),
+		WithResponseHeaders("Content-Type", "Set-Cookie")))
+	r.GET("/", func(c *gin.Context) {
+		c.SetCookie("session", "abc", 60, "/", "", false, true)
+		c.JSON(200, "Hello world!")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Client-Version", "1.2.3")
+	req.Header.Set("Authorization", "Bearer abc")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var aggregate struct {
+		Summary struct {
+			RequestHeaders  map[string]string `json:"request-headers"`
+			ResponseHeaders map[string]string `json:"response-headers"`
+		} `json:"request-summary-info"`
+	}
+	is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+	is.Equal(aggregate.Summary.RequestHeaders, map[string]string{"X-Client-Version": "1.2.3", "Authorization": RedactedValue})
+	is.Equal(aggregate.Summary.ResponseHeaders, map[string]string{"Content-Type": "application/json; charset=utf-8", "Set-Cookie": RedactedValue})
+}
@@ -0,0 +1,215 @@
+package ginlogrus
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator - generates a new request id, when one isn't found by the other RequestIDResolver(s)
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc - an adapter to allow the use of ordinary functions as an IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID - calls f()
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDGenerator - generates random (version 4) uuids, and it's the default IDGenerator
+var UUIDGenerator IDGenerator = IDGeneratorFunc(func() string {
+	return uuid.New().String()
+})
+
+// crockford - the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator - generates ULIDs (https://github.com/ulid/spec)
+type ulidGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	lastMS  uint64
+	lastRnd [10]byte
+}
+
+// NewULIDGenerator - create an IDGenerator for ULIDs, which are 26 characters and sort by time.  ULIDs created in the
+// same millisecond are monotonic
+func NewULIDGenerator() IDGenerator {
+	return &ulidGenerator{now: time.Now}
+}
+
+// NewID - generate a ULID
+func (g *ulidGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := uint64(g.now().UnixNano() / int64(time.Millisecond))
+	if ms <= g.lastMS {
+		// same (or an earlier) millisecond, so increment the random part to stay monotonic
+		ms = g.lastMS
+		for i := len(g.lastRnd) - 1; i >= 0; i-- {
+			g.lastRnd[i]++
+			if g.lastRnd[i] != 0 {
+				break
+			}
+		}
+	} else {
+		mustReadRandom(g.lastRnd[:])
+	}
+	g.lastMS = ms
+
+	var id [16]byte
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	copy(id[6:], g.lastRnd[:])
+	return encodeCrockford(id)
+}
+
+// encodeCrockford - encode the 128 bit id as 26 Crockford base32 characters
+func encodeCrockford(id [16]byte) string {
+	n := new(big.Int).SetBytes(id[:])
+	out := make([]byte, 26)
+	mask := big.NewInt(31)
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(out)
+}
+
+// base62 - the alphabet used by KSUIDs
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ksuidEpoch - KSUID timestamps are seconds since 2014-05-13T16:53:20Z
+const ksuidEpoch = 1400000000
+
+// ksuidGenerator - generates KSUIDs (https://github.com/segmentio/ksuid)
+type ksuidGenerator struct {
+	now func() time.Time
+}
+
+// NewKSUIDGenerator - create an IDGenerator for KSUIDs, which are 27 characters and sort by time (to the second)
+func NewKSUIDGenerator() IDGenerator {
+	return &ksuidGenerator{now: time.Now}
+}
+
+// NewID - generate a KSUID
+func (g *ksuidGenerator) NewID() string {
+	var id [20]byte
+	binary.BigEndian.PutUint32(id[0:4], uint32(g.now().Unix()-ksuidEpoch))
+	mustReadRandom(id[4:])
+
+	n := new(big.Int).SetBytes(id[:])
+	out := make([]byte, 27)
+	base := big.NewInt(62)
+	mod := new(big.Int)
+	for i := len(out) - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62[mod.Int64()]
+	}
+	return string(out)
+}
+
+// SnowflakeEpoch - the epoch (2020-01-01T00:00:00Z) used for Snowflake timestamps
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeGenerator - generates Snowflake style ids
+type snowflakeGenerator struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	node     uint64
+	lastMS   uint64
+	sequence uint64
+}
+
+// NewSnowflakeGenerator - create an IDGenerator for Snowflake style ids: 41 bits of milliseconds since the SnowflakeEpoch,
+// 10 bits of node id and a 12 bit sequence, formatted as a 20 digit (zero padded) decimal number, so they sort as strings.
+// The node must be between 0 and 1023
+func NewSnowflakeGenerator(node uint16) (IDGenerator, error) {
+	if node > snowflakeMaxNode {
+		return nil, fmt.Errorf("snowflake node %d is more than the max of %d", node, snowflakeMaxNode)
+	}
+	return &snowflakeGenerator{now: time.Now, node: uint64(node)}, nil
+}
+
+// NewID - generate a Snowflake id
+func (g *snowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := g.millis()
+	if ms < g.lastMS {
+		// the clock went backwards, so stick with the last millisecond
+		ms = g.lastMS
+	}
+	if ms == g.lastMS {
+		g.sequence = (g.sequence + 1) & snowflakeMaxSequence
+		if g.sequence == 0 {
+			// out of ids for this millisecond, so wait for the next one
+			for ms <= g.lastMS {
+				time.Sleep(100 * time.Microsecond)
+				ms = g.millis()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMS = ms
+	id := ms<<(snowflakeNodeBits+snowflakeSequenceBits) | g.node<<snowflakeSequenceBits | g.sequence
+	// zero padded, so the ids sort as strings (they'd go from 18 to 19 digits in 2027)
+	return fmt.Sprintf("%020d", id)
+}
+
+// millis - milliseconds since the SnowflakeEpoch
+func (g *snowflakeGenerator) millis() uint64 {
+	return uint64(g.now().Sub(SnowflakeEpoch) / time.Millisecond)
+}
+
+// deterministicGenerator - generates a repeatable sequence of uuids
+type deterministicGenerator struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewDeterministicGenerator - create an IDGenerator which generates the same sequence of (version 4 formatted) uuids for
+// the same seed.  It's handy for golden-file tests of the aggregate output, but don't use it in production
+func NewDeterministicGenerator(seed int64) IDGenerator {
+	return &deterministicGenerator{rnd: rand.New(rand.NewSource(seed))}
+}
+
+// NewID - generate the next uuid in the sequence
+func (g *deterministicGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id, err := uuid.NewRandomFromReader(g.rnd)
+	if err != nil {
+		// a math/rand.Rand never fails to read
+		panic(err)
+	}
+	return id.String()
+}
+
+// mustReadRandom - fill b from crypto/rand
+func mustReadRandom(b []byte) {
+	if _, err := crand.Read(b); err != nil {
+		panic(fmt.Sprintf("unable to read random bytes: %v", err))
+	}
+}
@@ -0,0 +1,153 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+// fakeClock - returns the times in order, and then sticks with the last one
+func fakeClock(times ...time.Time) func() time.Time {
+	i := 0
+	return func() time.Time {
+		t := times[i]
+		if i < len(times)-1 {
+			i++
+		}
+		return t
+	}
+}
+
+func assertSorted(t *testing.T, name string, ids []string) {
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("%s ids aren't sorted: %v", name, ids)
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("%s generated a duplicate id: %v", name, id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestULIDGenerator(t *testing.T) {
+	base := time.Date(2019, 2, 6, 13, 24, 6, 0, time.UTC)
+	g := &ulidGenerator{now: fakeClock(base, base, base, base.Add(time.Millisecond), base.Add(-time.Second), base.Add(time.Hour))}
+	ids := []string{}
+	for i := 0; i < 6; i++ {
+		id := g.NewID()
+		if len(id) != 26 || strings.Trim(id, crockford) != "" {
+			t.Errorf("ULID %v isn't 26 Crockford base32 characters", id)
+		}
+		ids = append(ids, id)
+	}
+	assertSorted(t, "ULID", ids)
+	// the first 10 characters are the timestamp
+	if ids[0][:10] != ids[2][:10] || ids[0][:10] == ids[3][:10] {
+		t.Errorf("unexpected ULID timestamps: %v", ids)
+	}
+	if id := NewULIDGenerator().NewID(); id[:1] != "0" {
+		t.Errorf("ULID %v should start with 0 until the year 10889", id)
+	}
+}
+
+func TestKSUIDGenerator(t *testing.T) {
+	base := time.Date(2019, 2, 6, 13, 24, 6, 0, time.UTC)
+	g := &ksuidGenerator{now: fakeClock(base, base.Add(time.Second), base.Add(time.Minute), base.Add(time.Hour))}
+	ids := []string{}
+	for i := 0; i < 4; i++ {
+		id := g.NewID()
+		if len(id) != 27 || strings.Trim(id, base62) != "" {
+			t.Errorf("KSUID %v isn't 27 base62 characters", id)
+		}
+		ids = append(ids, id)
+	}
+	assertSorted(t, "KSUID", ids)
+}
+
+func TestSnowflakeGenerator(t *testing.T) {
+	if _, err := NewSnowflakeGenerator(1024); err == nil {
+		t.Error("NewSnowflakeGenerator() expected an error for node 1024")
+	}
+	gen, err := NewSnowflakeGenerator(7)
+	if err != nil {
+		t.Fatal("NewSnowflakeGenerator() error: ", err)
+	}
+	g := gen.(*snowflakeGenerator)
+	base := time.Date(2023, 2, 6, 13, 24, 6, 0, time.UTC)
+	g.now = fakeClock(base, base, base.Add(time.Millisecond), base.Add(-time.Second))
+	last := uint64(0)
+	for i := 0; i < 4; i++ {
+		id, err := strconv.ParseUint(g.NewID(), 10, 64)
+		if err != nil {
+			t.Fatal("snowflake id isn't a number: ", err)
+		}
+		if id <= last {
+			t.Errorf("snowflake id %d isn't more than %d", id, last)
+		}
+		if node := (id >> snowflakeSequenceBits) & snowflakeMaxNode; node != 7 {
+			t.Errorf("snowflake node = %d, want 7", node)
+		}
+		last = id
+	}
+
+	// the ids sort as strings when the number goes from 18 to 19 digits
+	digits19 := time.Duration((1e18>>(snowflakeNodeBits+snowflakeSequenceBits))+1) * time.Millisecond
+	g.now = fakeClock(SnowflakeEpoch.Add(digits19-time.Millisecond), SnowflakeEpoch.Add(digits19))
+	ids := []string{g.NewID(), g.NewID()}
+	for _, id := range ids {
+		if len(id) != 20 {
+			t.Errorf("snowflake id %q isn't 20 digits", id)
+		}
+	}
+	if n := strings.TrimLeft(ids[1], "0"); len(n) != 19 || len(strings.TrimLeft(ids[0], "0")) != 18 {
+		t.Errorf("snowflake ids %v don't go from 18 to 19 digits", ids)
+	}
+	assertSorted(t, "snowflake", ids)
+}
+
+func TestDeterministicGenerator(t *testing.T) {
+	g1 := NewDeterministicGenerator(42)
+	g2 := NewDeterministicGenerator(42)
+	g3 := NewDeterministicGenerator(43)
+	for i := 0; i < 3; i++ {
+		id1, id2, id3 := g1.NewID(), g2.NewID(), g3.NewID()
+		if id1 != id2 {
+			t.Errorf("same seed generated %v and %v", id1, id2)
+		}
+		if id1 == id3 {
+			t.Errorf("different seeds generated the same id %v", id1)
+		}
+		if len(id1) != 36 {
+			t.Errorf("%v isn't a uuid", id1)
+		}
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	is := is.New(t)
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithWriter(l),
+		WithIDGenerator(IDGeneratorFunc(func() string { return "golden-id" }))))
+	r.GET("/", func(c *gin.Context) {
+		GetCtxLogger(c).Info("test-entry-1")
+		c.JSON(200, "Hello world!")
+	})
+	w := performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+	is.True(strings.Contains(l.String(), `"requestID":"golden-id"`))
+}
@@ -0,0 +1,42 @@
+package ginlogrus
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SummaryLevelFunc - choose the logrus.Level for the request summary, when the request is over
+type SummaryLevelFunc func(c *gin.Context, latency time.Duration) logrus.Level
+
+// StatusLevel - a SummaryLevelFunc which uses Error for gin errors and 5xx responses, Warn for 4xx responses and requests
+// slower than slow (0 disables it), and Info for everything else
+func StatusLevel(slow time.Duration) SummaryLevelFunc {
+	return func(c *gin.Context, latency time.Duration) logrus.Level {
+		status := c.Writer.Status()
+		switch {
+		case len(c.Errors) > 0 || status >= http.StatusInternalServerError:
+			return logrus.ErrorLevel
+		case status >= http.StatusBadRequest || (slow > 0 && latency >= slow):
+			return logrus.WarnLevel
+		}
+		return logrus.InfoLevel
+	}
+}
+
+// summaryLevel - the level for the request summary: Error for gin errors and panics and Info for everything else,
+// unless there's a SummaryLevelFunc.  Panic and Fatal are logged as Error, since logging the summary mustn't panic or exit
+func summaryLevel(c *gin.Context, latency time.Duration, panicked bool, f SummaryLevelFunc) logrus.Level {
+	if f == nil {
+		if len(c.Errors) > 0 || panicked {
+			return logrus.ErrorLevel
+		}
+		return logrus.InfoLevel
+	}
+	if level := f(c, latency); level > logrus.ErrorLevel {
+		return level
+	}
+	return logrus.ErrorLevel
+}
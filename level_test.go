@@ -0,0 +1,78 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStatusLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		err      bool
+		latency  time.Duration
+		panicked bool
+		f        SummaryLevelFunc
+		want     logrus.Level
+	}{
+		{name: "default-ok", status: 200, want: logrus.InfoLevel},
+		{name: "default-5xx", status: 500, want: logrus.InfoLevel},
+		{name: "default-error", status: 200, err: true, want: logrus.ErrorLevel},
+		{name: "default-panic", status: 500, panicked: true, want: logrus.ErrorLevel},
+		{name: "status-ok", status: 200, f: StatusLevel(time.Second), want: logrus.InfoLevel},
+		{name: "status-5xx", status: 503, f: StatusLevel(time.Second), want: logrus.ErrorLevel},
+		{name: "status-4xx", status: 404, f: StatusLevel(time.Second), want: logrus.WarnLevel},
+		{name: "status-error", status: 200, err: true, f: StatusLevel(time.Second), want: logrus.ErrorLevel},
+		{name: "status-slow", status: 200, latency: 2 * time.Second, f: StatusLevel(time.Second), want: logrus.WarnLevel},
+		{name: "status-slow-disabled", status: 200, latency: 2 * time.Second, f: StatusLevel(0), want: logrus.InfoLevel},
+		{name: "custom", status: 200, f: func(c *gin.Context, latency time.Duration) logrus.Level { return logrus.DebugLevel }, want: logrus.DebugLevel},
+		{name: "custom-panic-is-error", status: 200, f: func(c *gin.Context, latency time.Duration) logrus.Level { return logrus.PanicLevel }, want: logrus.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Status(tt.status)
+			if tt.err {
+				_ = c.Error(errors.New("boom"))
+			}
+			if got := summaryLevel(c, tt.latency, tt.panicked, tt.f); got != tt.want {
+				t.Errorf("summaryLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithStatusLevel(t *testing.T) {
+	is := is.New(t)
+	var summary, aggregate bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &summary
+	logger.Formatter = &logrus.JSONFormatter{}
+	gin.SetMode(gin.DebugMode)
+
+	r := gin.New()
+	r.Use(New(WithLogger(logger), WithStatusLevel(time.Second)))
+	r.GET("/", func(c *gin.Context) { c.JSON(503, "Hello fail!") })
+	performRequest("GET", "/", r)
+	is.True(strings.Contains(summary.String(), `"level":"error"`))
+	is.True(strings.Contains(summary.String(), `"status":503`))
+
+	r = gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(&aggregate), WithStatusLevel(time.Second)))
+	r.GET("/", func(c *gin.Context) { c.JSON(404, "Hello missing!") })
+	performRequest("GET", "/", r)
+	var out struct {
+		Summary map[string]interface{} `json:"request-summary-info"`
+	}
+	is.NoErr(json.Unmarshal(aggregate.Bytes(), &out))
+	is.Equal(out.Summary["level"], "warning")
+}
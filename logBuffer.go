@@ -2,22 +2,42 @@ package ginlogrus
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"strings"
+	"io"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/mitchellh/copystructure"
+	"github.com/sirupsen/logrus"
 )
 
-// LogBuffer - implement io.Writer inferface to append to a string
+// LogBuffer - stores aggregate log entries as structured LogEntry(s).  It implements the logrus.Formatter interface, so
+// a logrus.Logger which uses the LogBuffer as both its Formatter and Out will store every entry in the buffer.  It also
+// implements the io.Writer interface for any other writers.  It's safe for concurrent writers and readers.
+// The Buff field is gone since the entries aren't stored as JSON anymore: use String(), Entries() or Length() instead
 type LogBuffer struct {
-	Buff      strings.Builder
+	entries   []LogEntry
+	size      int
+	buffMU    *sync.RWMutex
 	header    map[string]interface{}
 	headerMU  *sync.RWMutex
 	AddBanner bool
 	banner    string
 	MaxSize   uint
+
+	overflowPolicy      OverflowPolicy
+	overflowKeepFirst   uint
+	overflowKeepLast    uint
+	overflowSpillWriter io.Writer
+	beforeSpill         func() // called before a partial aggregate is spilled (e.g. to store the request summary)
+	droppedEntries      int
+	droppedBytes        int
+	spills              int
+
+	redactor    *Redactor
+	encoder     AggregateEncoder
+	summaryTime time.Time // the time of the request summary, so encoders don't parse its "time" (see WithTimeFormat())
 }
 
 // NewLogBuffer - create a LogBuffer and initialize it
@@ -27,17 +47,35 @@ func NewLogBuffer(opt ...LogBufferOption) LogBuffer {
 		o(&opts)
 	}
 	b := LogBuffer{
+		buffMU:    &sync.RWMutex{},
 		header:    opts.withHeaders,
 		headerMU:  &sync.RWMutex{},
 		AddBanner: opts.addBanner,
 		MaxSize:   opts.maxSize,
+
+		overflowPolicy:      opts.overflowPolicy,
+		overflowKeepFirst:   opts.overflowKeepFirst,
+		overflowKeepLast:    opts.overflowKeepLast,
+		overflowSpillWriter: opts.overflowSpillWriter,
+
+		redactor: opts.redactor,
+		encoder:  opts.encoder,
+	}
+	if b.redactor != nil && b.header != nil {
+		b.header = b.redactor.Fields(b.header)
 	}
 	b.SetCustomBanner(opts.banner)
 	return b
 }
 
-// StoreHeader - store a header
+// StoreHeader - store a header (after it's redacted, when the LogBuffer has a Redactor)
 func (b *LogBuffer) StoreHeader(k string, v interface{}) {
+	if b.redactor != nil {
+		var keep bool
+		if v, keep = b.redactor.Value(k, v); !keep {
+			return
+		}
+	}
 	b.headerMU.Lock()
 	if b.header == nil {
 		b.header = make(map[string]interface{})
@@ -46,11 +84,16 @@ func (b *LogBuffer) StoreHeader(k string, v interface{}) {
 	b.headerMU.Unlock()
 }
 
+// storeSummary - store the request summary header and its time
+func (b *LogBuffer) storeSummary(fields logrus.Fields, t time.Time) {
+	b.StoreHeader(summaryHeaderKey, fields)
+	b.headerMU.Lock()
+	b.summaryTime = t
+	b.headerMU.Unlock()
+}
+
 // DeleteHeader - delete a header
 func (b *LogBuffer) DeleteHeader(k string) {
-	if b.header == nil {
-		return
-	}
 	b.headerMU.Lock()
 	delete(b.header, k)
 	b.headerMU.Unlock()
@@ -58,9 +101,6 @@ func (b *LogBuffer) DeleteHeader(k string) {
 
 // GetHeader - get a header
 func (b *LogBuffer) GetHeader(k string) (interface{}, bool) {
-	if b.header == nil {
-		return nil, false
-	}
 	b.headerMU.RLock()
 	r, ok := b.header[k]
 	b.headerMU.RUnlock()
@@ -80,10 +120,10 @@ func (b *LogBuffer) GetAllHeaders() (map[string]interface{}, error) {
 
 // CopyHeader - copy a header
 func CopyHeader(dst *LogBuffer, src *LogBuffer) {
-	src.headerMU.Lock()
+	src.headerMU.RLock()
 	dup, err := copystructure.Copy(src.header)
 	dupBanner := src.AddBanner
-	src.headerMU.Unlock()
+	src.headerMU.RUnlock()
 
 	dst.headerMU.Lock()
 	if err != nil {
@@ -95,44 +135,206 @@ func CopyHeader(dst *LogBuffer, src *LogBuffer) {
 	dst.headerMU.Unlock()
 }
 
-// Write - simply append to the strings.Buffer but add a comma too
+// Format - implement the logrus.Formatter interface by storing the entry as a LogEntry.  Nothing is returned to be
+// written to the logger's Out
+func (b *LogBuffer) Format(e *logrus.Entry) ([]byte, error) {
+	entry := newLogEntry(e)
+	if b.redactor != nil {
+		entry = b.redactor.Entry(entry)
+	}
+	if err := b.store(entry); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// Write - store the data as a LogEntry.  JSON objects (like the ones written by logrus.JSONFormatter) are
+// stored as structured entries, and anything else is stored as the entry's message
 func (b *LogBuffer) Write(data []byte) (n int, err error) {
 	newData := bytes.TrimSuffix(data, []byte("\n"))
+	if len(newData) == 0 {
+		return 0, nil
+	}
+	entry := parseLogEntry(newData)
+	if b.redactor != nil {
+		entry = b.redactor.Entry(entry)
+	}
+	if err := b.store(entry); err != nil {
+		return 0, err
+	}
+	return len(newData) + 1, nil
+}
 
-	if len(newData)+b.Buff.Len() > int(b.MaxSize) {
-		return 0, fmt.Errorf("write failed: buffer MaxSize = %d, current len = %d, attempted to write len = %d, data == %s", b.MaxSize, b.Buff.Len(), len(newData), newData)
+// store - append the entry if there's room for it in the buffer, otherwise apply the OverflowPolicy
+func (b *LogBuffer) store(e LogEntry) error {
+	e.size = e.measure()
+	b.buffMU.Lock()
+	defer b.buffMU.Unlock()
+	if !b.fits(e.size) {
+		if ok, err := b.overflow(e, e.size); !ok {
+			return err
+		}
 	}
-	return b.Buff.Write(append(newData, []byte(",")...))
+	b.trimLast()
+	b.entries = append(b.entries, e)
+	b.size += e.size + 1 // plus the comma that separates entries
+	return nil
 }
 
 // Length - return the length of the aggregate log buffer
 func (b *LogBuffer) Length() int {
-	return b.Buff.Len()
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	return b.size
 }
 
-// String - output the strings.Builder as one aggregated JSON object
-func (b *LogBuffer) String() string {
-	var str strings.Builder
-	str.WriteString("{")
-	if b.header != nil && len(b.header) != 0 {
-		b.headerMU.RLock()
-		hdr, err := json.Marshal(b.header)
-		b.headerMU.RUnlock()
+// Entries - return a copy of the entries in the aggregate log buffer
+func (b *LogBuffer) Entries() []LogEntry {
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	entries := make([]LogEntry, 0, len(b.entries))
+	for _, e := range b.entries {
+		entries = append(entries, e.copy())
+	}
+	return entries
+}
+
+// CountByLevel - return the number of entries for each logrus.Level
+func (b *LogBuffer) CountByLevel() map[logrus.Level]int {
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	counts := map[logrus.Level]int{}
+	for _, e := range b.entries {
+		counts[e.Level]++
+	}
+	return counts
+}
+
+// Filter - only keep the entries where keep returns true
+func (b *LogBuffer) Filter(keep func(LogEntry) bool) {
+	b.buffMU.Lock()
+	defer b.buffMU.Unlock()
+	kept := b.entries[:0]
+	for _, e := range b.entries {
+		if keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	b.entries = kept
+	b.resize()
+}
+
+// Transform - replace every entry with the result of f (which is handy for redacting fields)
+func (b *LogBuffer) Transform(f func(LogEntry) LogEntry) {
+	b.buffMU.Lock()
+	defer b.buffMU.Unlock()
+	for i, e := range b.entries {
+		e = f(e)
+		e.size = e.measure()
+		b.entries[i] = e
+	}
+	b.resize()
+}
+
+// resize - recalculate the length of the buffer from the size of every entry, the caller must hold buffMU
+func (b *LogBuffer) resize() {
+	b.size = 0
+	for _, e := range b.entries {
+		b.size += e.size + 1
+	}
+}
+
+// Render - output the entries, one per line, using any logrus.Formatter (e.g. &logrus.TextFormatter{})
+func (b *LogBuffer) Render(f logrus.Formatter) ([]byte, error) {
+	var out bytes.Buffer
+	for _, e := range b.Entries() {
+		formatted, err := f.Format(e.logrusEntry())
 		if err != nil {
-			fmt.Println("Error encoding logBuffer JSON")
+			return nil, err
+		}
+		out.Write(formatted)
+	}
+	return out.Bytes(), nil
+}
+
+// String - output the entries as one aggregate, encoded by the buffer's AggregateEncoder (JSONEncoder by default)
+func (b *LogBuffer) String() string {
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	return b.encode(false)
+}
+
+// Aggregate - return a snapshot of the headers and entries for an AggregateEncoder
+func (b *LogBuffer) Aggregate() Aggregate {
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	return b.aggregate(false)
+}
+
+// aggregate - a snapshot of the headers and entries, the caller must hold buffMU
+func (b *LogBuffer) aggregate(partial bool) Aggregate {
+	a := Aggregate{
+		Entries:  make([]LogEntry, 0, len(b.entries)+1),
+		Overflow: b.overflowSummary(),
+		Partial:  partial,
+	}
+	b.headerMU.RLock()
+	if len(b.header) != 0 {
+		a.Header = make(map[string]interface{}, len(b.header))
+		for k, v := range b.header {
+			a.Header[k] = v
 		}
-		str.Write(hdr[1 : len(hdr)-1])
-		str.WriteString(",")
 	}
-	str.WriteString("\"entries\":[" + strings.TrimSuffix(b.Buff.String(), ",") + "]")
 	if b.AddBanner {
-		str.WriteString(b.banner)
+		a.Banner = b.banner
+	}
+	a.SummaryTime = b.summaryTime
+	b.headerMU.RUnlock()
+	markerAt, marker, truncated := b.truncatedMarker()
+	for i, e := range b.entries {
+		if truncated && i == markerAt {
+			a.Entries = append(a.Entries, marker)
+		}
+		a.Entries = append(a.Entries, e)
+	}
+	if truncated && markerAt == len(b.entries) {
+		a.Entries = append(a.Entries, marker)
+	}
+	return a
+}
+
+// writeTo - write the aggregate to w (see AggregateWriter)
+func (b *LogBuffer) writeTo(w io.Writer) {
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	writeAggregate(w, b.aggregate(false), b.encode(false))
+}
+
+// encode - output the entries as one aggregate, the caller must hold buffMU.  When the buffer's AggregateEncoder fails,
+// the aggregate is encoded by the JSONEncoder, which always produces valid JSON
+func (b *LogBuffer) encode(partial bool) string {
+	a := b.aggregate(partial)
+	if b.encoder != nil {
+		out, err := b.encoder.Encode(a)
+		if err == nil {
+			return string(out)
+		}
+		fmt.Fprintln(os.Stderr, "Error encoding logBuffer aggregate:", err)
+	}
+	out, _ := JSONEncoder().Encode(a)
+	return string(out)
+}
+
+// appendEntryJSON - append the entry encoded as JSON, skipping entries that can't be encoded
+func appendEntryJSON(entries []string, e LogEntry) []string {
+	j, err := e.MarshalJSON()
+	if err != nil {
+		return entries
 	}
-	str.WriteString("}\n")
-	return str.String()
+	return append(entries, string(j))
 }
 
 // SetCustomBanner allows a custom banner to be set after the NewLogBuffer() has been used
 func (b *LogBuffer) SetCustomBanner(banner string) {
-	b.banner = fmt.Sprintf(",\"banner\":\"%s\"", banner)
+	b.banner = banner
 }
@@ -1,14 +1,22 @@
 package ginlogrus
 
+import "io"
+
 const DefaultBanner = "[GIN] --------------------------------------------------------------- GinLogrusWithTracing ----------------------------------------------------------------"
 
 // LogBufferOption - define options for LogBuffer
 type LogBufferOption func(*logBufferOptions)
 type logBufferOptions struct {
-	addBanner   bool
-	withHeaders map[string]interface{}
-	maxSize     uint
-	banner      string
+	addBanner           bool
+	withHeaders         map[string]interface{}
+	maxSize             uint
+	banner              string
+	overflowPolicy      OverflowPolicy
+	overflowKeepFirst   uint
+	overflowKeepLast    uint
+	overflowSpillWriter io.Writer
+	redactor            *Redactor
+	encoder             AggregateEncoder
 }
 
 // DefaultLogBufferMaxSize - avg single spaced page contains 3k chars, so 100k == 33 pages which is a reasonable max
@@ -52,3 +60,41 @@ func WithCustomBanner(b string) LogBufferOption {
 		o.banner = b
 	}
 }
+
+// WithOverflowPolicy specifies what happens when storing an entry would exceed the max size, the default is OverflowError
+func WithOverflowPolicy(p OverflowPolicy) LogBufferOption {
+	return func(o *logBufferOptions) {
+		o.overflowPolicy = p
+	}
+}
+
+// WithOverflowKeepFirstLast sets the OverflowKeepFirstLast policy, which always keeps the first N entries and at most
+// the last M entries once the max size is reached.  When last == 0, as many of the last entries as fit are kept
+func WithOverflowKeepFirstLast(first, last uint) LogBufferOption {
+	return func(o *logBufferOptions) {
+		o.overflowPolicy = OverflowKeepFirstLast
+		o.overflowKeepFirst = first
+		o.overflowKeepLast = last
+	}
+}
+
+// WithOverflowSpillWriter specifies where partial aggregates are written by the OverflowSpill policy
+func WithOverflowSpillWriter(w io.Writer) LogBufferOption {
+	return func(o *logBufferOptions) {
+		o.overflowSpillWriter = w
+	}
+}
+
+// WithBufferRedactor - define an Option func for redacting every entry and header before it's stored in the LogBuffer
+func WithBufferRedactor(r *Redactor) LogBufferOption {
+	return func(o *logBufferOptions) {
+		o.redactor = r
+	}
+}
+
+// WithEncoder - define an Option func for passing in the AggregateEncoder used by String(), the default is JSONEncoder()
+func WithEncoder(e AggregateEncoder) LogBufferOption {
+	return func(o *logBufferOptions) {
+		o.encoder = e
+	}
+}
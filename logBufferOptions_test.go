@@ -64,3 +64,12 @@ func TestWithHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestWithOverflowKeepFirstLast(t *testing.T) {
+	opts := defaultLogBufferOptions()
+	f := WithOverflowKeepFirstLast(3, 5)
+	f(&opts)
+	if opts.overflowPolicy != OverflowKeepFirstLast || opts.overflowKeepFirst != 3 || opts.overflowKeepLast != 5 {
+		t.Errorf("WithOverflowKeepFirstLast() = %v %d %d, want %v 3 5", opts.overflowPolicy, opts.overflowKeepFirst, opts.overflowKeepLast, OverflowKeepFirstLast)
+	}
+}
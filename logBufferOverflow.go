@@ -0,0 +1,162 @@
+package ginlogrus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OverflowPolicy - defines what a LogBuffer does when storing an entry would exceed its MaxSize
+type OverflowPolicy int
+
+const (
+	// OverflowError - drop the new entry and return an error (which logrus prints to stderr).  This is the default
+	OverflowError OverflowPolicy = iota
+	// OverflowDropNewest - silently drop the new entry
+	OverflowDropNewest
+	// OverflowDropOldest - drop the oldest entries until the new entry fits (a ring buffer)
+	OverflowDropOldest
+	// OverflowKeepFirstLast - always keep the first N entries, and drop the oldest entries after them until the new
+	// entry fits, keeping at most the last M.  A truncated marker entry is added where entries were dropped
+	OverflowKeepFirstLast
+	// OverflowSpill - write the buffered entries to the spill writer as a partial aggregate, and then continue with
+	// an empty buffer
+	OverflowSpill
+)
+
+var overflowPolicyNames = map[OverflowPolicy]string{
+	OverflowError:         "error",
+	OverflowDropNewest:    "drop-newest",
+	OverflowDropOldest:    "drop-oldest",
+	OverflowKeepFirstLast: "keep-first-last",
+	OverflowSpill:         "spill",
+}
+
+// String - the name of the policy
+func (p OverflowPolicy) String() string {
+	if n, ok := overflowPolicyNames[p]; ok {
+		return n
+	}
+	return fmt.Sprintf("unknown(%d)", int(p))
+}
+
+// OverflowSummary - what's added to the aggregate when entries were dropped or spilled
+type OverflowSummary struct {
+	Policy         string `json:"policy"`
+	DroppedEntries int    `json:"dropped-entries"`
+	DroppedBytes   int    `json:"dropped-bytes"`
+	Spills         int    `json:"spills,omitempty"`
+}
+
+// overflow - handle an entry that doesn't fit, based on the buffer's OverflowPolicy.  It returns true when the entry
+// should still be stored, and the caller must hold buffMU
+func (b *LogBuffer) overflow(e LogEntry, size int) (bool, error) {
+	switch b.overflowPolicy {
+	case OverflowDropNewest:
+		b.drop(size)
+		return false, nil
+	case OverflowDropOldest, OverflowKeepFirstLast:
+		for !b.fits(size) && len(b.entries) > b.keepFirst() {
+			b.evict(b.keepFirst())
+		}
+	case OverflowSpill:
+		b.spill()
+	default:
+		b.drop(size)
+		return false, fmt.Errorf("write failed: buffer MaxSize = %d, current len = %d, attempted to write len = %d, msg == %s", b.MaxSize, b.size, size, e.Message)
+	}
+	if !b.fits(size) {
+		// it's never going to fit
+		b.drop(size)
+		return false, nil
+	}
+	return true, nil
+}
+
+// fits - will an entry of this size fit in the buffer
+func (b *LogBuffer) fits(size int) bool {
+	return size+b.size <= int(b.MaxSize)
+}
+
+// keepFirst - the number of entries at the start of the buffer which are never evicted
+func (b *LogBuffer) keepFirst() int {
+	if b.overflowPolicy == OverflowKeepFirstLast {
+		return int(b.overflowKeepFirst)
+	}
+	return 0
+}
+
+// trimLast - once entries have been dropped, keep at most the last M entries (including the one about to be stored)
+func (b *LogBuffer) trimLast() {
+	if b.overflowPolicy != OverflowKeepFirstLast || b.overflowKeepLast == 0 || b.droppedEntries == 0 {
+		return
+	}
+	for len(b.entries)-b.keepFirst() >= int(b.overflowKeepLast) && len(b.entries) > b.keepFirst() {
+		b.evict(b.keepFirst())
+	}
+}
+
+// evict - drop the entry at index i
+func (b *LogBuffer) evict(i int) {
+	b.drop(b.entries[i].size)
+	b.size -= b.entries[i].size + 1
+	b.entries = append(b.entries[:i], b.entries[i+1:]...)
+}
+
+// drop - count an entry that was dropped
+func (b *LogBuffer) drop(size int) {
+	b.droppedEntries++
+	b.droppedBytes += size
+}
+
+// spill - write the buffered entries to the spill writer as a partial aggregate and empty the buffer
+func (b *LogBuffer) spill() {
+	if b.overflowSpillWriter == nil || len(b.entries) == 0 {
+		return
+	}
+	if b.beforeSpill != nil {
+		b.beforeSpill()
+	}
+	writeAggregate(b.overflowSpillWriter, b.aggregate(true), b.encode(true))
+	b.spills++
+	b.entries = nil
+	b.size = 0
+}
+
+// Dropped - return the number of entries and bytes which were dropped because of the MaxSize
+func (b *LogBuffer) Dropped() (entries int, bytes int) {
+	b.buffMU.RLock()
+	defer b.buffMU.RUnlock()
+	return b.droppedEntries, b.droppedBytes
+}
+
+// truncatedMarker - the entry that's added where entries were dropped, the caller must hold buffMU
+func (b *LogBuffer) truncatedMarker() (int, LogEntry, bool) {
+	if b.droppedEntries == 0 || (b.overflowPolicy != OverflowDropOldest && b.overflowPolicy != OverflowKeepFirstLast) {
+		return 0, LogEntry{}, false
+	}
+	at := b.keepFirst()
+	if at > len(b.entries) {
+		at = len(b.entries)
+	}
+	return at, LogEntry{
+		Time:    time.Now(),
+		Level:   logrus.WarnLevel,
+		Message: fmt.Sprintf("truncated: %d entries (%d bytes) were dropped", b.droppedEntries, b.droppedBytes),
+		Fields:  logrus.Fields{},
+	}, true
+}
+
+// overflowSummary - the overflow summary or nil if nothing was dropped or spilled, the caller must hold buffMU
+func (b *LogBuffer) overflowSummary() *OverflowSummary {
+	if b.droppedEntries == 0 && b.spills == 0 {
+		return nil
+	}
+	return &OverflowSummary{
+		Policy:         b.overflowPolicy.String(),
+		DroppedEntries: b.droppedEntries,
+		DroppedBytes:   b.droppedBytes,
+		Spills:         b.spills,
+	}
+}
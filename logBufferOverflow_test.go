@@ -0,0 +1,216 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+// entry-N is always 13 bytes when written, so every entry uses 14 bytes of the buffer
+func writeTestEntries(t *testing.T, b *LogBuffer, n int) {
+	for i := 0; i < n; i++ {
+		if _, err := b.Write([]byte(fmt.Sprintf("entry-%d-abcde", i))); err != nil {
+			t.Log("LogBuffer.Write() error == ", err)
+		}
+	}
+}
+
+func TestLogBuffer_Overflow(t *testing.T) {
+	tests := []struct {
+		name           string
+		opt            []LogBufferOption
+		writes         int
+		wantMsgs       []string
+		wantDropped    int
+		wantTruncated  bool
+		wantOverflowed bool
+	}{
+		{
+			name:     "fits",
+			opt:      []LogBufferOption{WithMaxSize(100), WithOverflowPolicy(OverflowDropOldest)},
+			writes:   5,
+			wantMsgs: []string{"entry-0-abcde", "entry-1-abcde", "entry-2-abcde", "entry-3-abcde", "entry-4-abcde"},
+		},
+		{
+			name:           "error",
+			opt:            []LogBufferOption{WithMaxSize(42)},
+			writes:         5,
+			wantMsgs:       []string{"entry-0-abcde", "entry-1-abcde", "entry-2-abcde"},
+			wantDropped:    2,
+			wantOverflowed: true,
+		},
+		{
+			name:           "drop-newest",
+			opt:            []LogBufferOption{WithMaxSize(42), WithOverflowPolicy(OverflowDropNewest)},
+			writes:         5,
+			wantMsgs:       []string{"entry-0-abcde", "entry-1-abcde", "entry-2-abcde"},
+			wantDropped:    2,
+			wantOverflowed: true,
+		},
+		{
+			name:           "drop-oldest",
+			opt:            []LogBufferOption{WithMaxSize(42), WithOverflowPolicy(OverflowDropOldest)},
+			writes:         5,
+			wantMsgs:       []string{"truncated: 2 entries (26 bytes) were dropped", "entry-2-abcde", "entry-3-abcde", "entry-4-abcde"},
+			wantDropped:    2,
+			wantTruncated:  true,
+			wantOverflowed: true,
+		},
+		{
+			name:           "keep-first-last",
+			opt:            []LogBufferOption{WithMaxSize(70), WithOverflowKeepFirstLast(2, 2)},
+			writes:         10,
+			wantMsgs:       []string{"entry-0-abcde", "entry-1-abcde", "truncated: 6 entries (78 bytes) were dropped", "entry-8-abcde", "entry-9-abcde"},
+			wantDropped:    6,
+			wantTruncated:  true,
+			wantOverflowed: true,
+		},
+		{
+			name:           "keep-first-as-many-as-fit",
+			opt:            []LogBufferOption{WithMaxSize(56), WithOverflowKeepFirstLast(1, 0)},
+			writes:         6,
+			wantMsgs:       []string{"entry-0-abcde", "truncated: 2 entries (26 bytes) were dropped", "entry-3-abcde", "entry-4-abcde", "entry-5-abcde"},
+			wantDropped:    2,
+			wantTruncated:  true,
+			wantOverflowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewLogBuffer(tt.opt...)
+			writeTestEntries(t, &b, tt.writes)
+
+			var got struct {
+				Entries []struct {
+					Msg string `json:"msg"`
+				} `json:"entries"`
+				Overflow *OverflowSummary `json:"overflow"`
+			}
+			if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+				t.Fatalf("LogBuffer.String() isn't valid JSON: %v: %s", err, b.String())
+			}
+			msgs := []string{}
+			for _, e := range got.Entries {
+				msgs = append(msgs, e.Msg)
+			}
+			if strings.Join(msgs, "|") != strings.Join(tt.wantMsgs, "|") {
+				t.Errorf("entries = %v, want %v", msgs, tt.wantMsgs)
+			}
+			if dropped, _ := b.Dropped(); dropped != tt.wantDropped {
+				t.Errorf("LogBuffer.Dropped() = %d, want %d", dropped, tt.wantDropped)
+			}
+			if (got.Overflow != nil) != tt.wantOverflowed {
+				t.Errorf("overflow = %v, wantOverflowed %v", got.Overflow, tt.wantOverflowed)
+			}
+			if got.Overflow != nil && got.Overflow.DroppedEntries != tt.wantDropped {
+				t.Errorf("overflow dropped-entries = %d, want %d", got.Overflow.DroppedEntries, tt.wantDropped)
+			}
+			if b.Length() > int(b.MaxSize) {
+				t.Errorf("LogBuffer.Length() = %d is more than MaxSize %d", b.Length(), b.MaxSize)
+			}
+		})
+	}
+}
+
+func TestLogBuffer_OverflowSpill(t *testing.T) {
+	var spilled bytes.Buffer
+	b := NewLogBuffer(WithMaxSize(42), WithOverflowPolicy(OverflowSpill), WithOverflowSpillWriter(&spilled), WithHeader("requestID", "abc"))
+	writeTestEntries(t, &b, 7)
+
+	partials := strings.Split(strings.TrimSuffix(spilled.String(), "\n"), "\n")
+	if len(partials) != 2 {
+		t.Fatalf("expected 2 partial aggregates and got %d: %s", len(partials), spilled.String())
+	}
+	for _, p := range partials {
+		if !strings.Contains(p, `"partial":true`) || !strings.Contains(p, `"requestID":"abc"`) {
+			t.Errorf("expected a partial aggregate with headers and got %s", p)
+		}
+	}
+	if !strings.Contains(partials[0], "entry-0-abcde") || !strings.Contains(partials[1], "entry-3-abcde") {
+		t.Errorf("unexpected partial aggregates: %v", partials)
+	}
+	final := b.String()
+	if !strings.Contains(final, "entry-6-abcde") || strings.Contains(final, "entry-0-abcde") || strings.Contains(final, `"partial"`) {
+		t.Errorf("unexpected final aggregate: %s", final)
+	}
+	if !strings.Contains(final, `"spills":2`) {
+		t.Errorf("expected the final aggregate to record the spills: %s", final)
+	}
+	if dropped, _ := b.Dropped(); dropped != 0 {
+		t.Errorf("LogBuffer.Dropped() = %d, want 0", dropped)
+	}
+}
+
+func TestWithOverflowSpill(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		target   string
+		wantOut  int // the number of aggregates written
+		wantPart int // how many of them are partial
+		wantID   string
+	}{
+		{"spilled", nil, "/", 3, 2, "spill-id"},
+		{"skipped", []Option{WithSkipPaths("/skip")}, "/skip", 0, 0, ""},
+		{"tail-success", []Option{WithTailLogging(0), WithTailSuccessSummary(false)}, "/", 0, 0, ""},
+		{"id-set-by-handler", []Option{WithContextTraceIDField("my-id")}, "/late", 3, 2, "late-id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out bytes.Buffer
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(New(append(tt.opts, WithAggregateLogging(true), WithWriter(&out),
+				WithIDGenerator(IDGeneratorFunc(func() string { return "spill-id" })),
+				WithLogBufferOptions(WithMaxSize(100), WithOverflowPolicy(OverflowSpill)))...))
+			handler := func(c *gin.Context) {
+				for i := 0; i < 6; i++ {
+					GetCtxLogger(c).Infof("entry-%d-abcdefghijklmnopqrstuvwxyz", i)
+				}
+				c.Status(200)
+			}
+			r.GET("/", handler)
+			r.GET("/skip", handler)
+			r.GET("/late", func(c *gin.Context) {
+				c.Set("my-id", "late-id")
+				handler(c)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.target, nil))
+
+			aggregates := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if out.Len() == 0 {
+				aggregates = nil
+			}
+			is.Equal(len(aggregates), tt.wantOut)
+			partials := 0
+			for _, a := range aggregates {
+				var aggregate struct {
+					Summary map[string]interface{} `json:"request-summary-info"`
+					Partial bool                   `json:"partial"`
+				}
+				is.NoErr(json.Unmarshal([]byte(a), &aggregate))
+				is.Equal(aggregate.Summary["requestID"], tt.wantID) // the partials can be matched to the request too
+				if aggregate.Partial {
+					partials++
+				}
+			}
+			is.Equal(partials, tt.wantPart)
+		})
+	}
+}
+
+func TestOverflowPolicy_String(t *testing.T) {
+	if got := OverflowKeepFirstLast.String(); got != "keep-first-last" {
+		t.Errorf("OverflowPolicy.String() = %v, want keep-first-last", got)
+	}
+	if got := OverflowPolicy(42).String(); got != "unknown(42)" {
+		t.Errorf("OverflowPolicy.String() = %v, want unknown(42)", got)
+	}
+}
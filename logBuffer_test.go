@@ -1,11 +1,17 @@
 package ginlogrus
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
 	"sync"
 	"testing"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestLogBuffer_String(t *testing.T) {
@@ -94,22 +100,22 @@ func TestNewLogBuffer(t *testing.T) {
 		{
 			name: "one",
 			opt:  []LogBufferOption{WithBanner(true), WithHeader("1", true)},
-			want: LogBuffer{AddBanner: true, header: map[string]interface{}{"1": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: ",\"banner\":\"[GIN] --------------------------------------------------------------- GinLogrusWithTracing ----------------------------------------------------------------\""},
+			want: LogBuffer{AddBanner: true, buffMU: &sync.RWMutex{}, header: map[string]interface{}{"1": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: DefaultBanner},
 		},
 		{
 			name: "two",
 			opt:  []LogBufferOption{WithHeader("1", "one"), WithHeader("2", true)},
-			want: LogBuffer{AddBanner: false, header: map[string]interface{}{"1": "one", "2": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: ",\"banner\":\"[GIN] --------------------------------------------------------------- GinLogrusWithTracing ----------------------------------------------------------------\""},
+			want: LogBuffer{AddBanner: false, buffMU: &sync.RWMutex{}, header: map[string]interface{}{"1": "one", "2": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: DefaultBanner},
 		},
 		{
 			name: "three",
 			opt:  []LogBufferOption{WithBanner(true), WithHeader("1", true), WithCustomBanner("custom")},
-			want: LogBuffer{AddBanner: true, header: map[string]interface{}{"1": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: ",\"banner\":\"custom\""},
+			want: LogBuffer{AddBanner: true, buffMU: &sync.RWMutex{}, header: map[string]interface{}{"1": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: "custom"},
 		},
 		{
 			name: "four",
 			opt:  []LogBufferOption{WithBanner(false), WithHeader("1", true)},
-			want: LogBuffer{AddBanner: false, header: map[string]interface{}{"1": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: ",\"banner\":\"[GIN] --------------------------------------------------------------- GinLogrusWithTracing ----------------------------------------------------------------\""},
+			want: LogBuffer{AddBanner: false, buffMU: &sync.RWMutex{}, header: map[string]interface{}{"1": true}, headerMU: &sync.RWMutex{}, MaxSize: DefaultLogBufferMaxSize, banner: DefaultBanner},
 		},
 	}
 	for _, tt := range tests {
@@ -219,3 +225,208 @@ func TestLogBuffer_Write(t *testing.T) {
 }
 
 var tooBigBuff = strings.Repeat("#", DefaultLogBufferMaxSize) + "1"
+
+func TestLogBuffer_Concurrency(t *testing.T) {
+	const writers = 20
+	const writesPerWriter = 50
+	buff := NewLogBuffer(WithBanner(true), WithHeader("initial", "header"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < writesPerWriter; j++ {
+				if _, err := buff.Write([]byte(fmt.Sprintf("{\"msg\":\"writer-%d-%d\"}\n", i, j))); err != nil {
+					t.Error("LogBuffer.Write() error: ", err)
+				}
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			k := fmt.Sprintf("hdr-%d", i)
+			for j := 0; j < writesPerWriter; j++ {
+				buff.StoreHeader(k, j)
+				buff.GetHeader(k)
+				if _, err := buff.GetAllHeaders(); err != nil {
+					t.Error("LogBuffer.GetAllHeaders() error: ", err)
+				}
+				if j%2 == 0 {
+					buff.DeleteHeader(k)
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				_ = buff.String()
+				_ = buff.Length()
+				dst := NewLogBuffer()
+				CopyHeader(&dst, &buff)
+			}
+		}()
+	}
+	wg.Wait()
+
+	out := buff.String()
+	for i := 0; i < writers; i++ {
+		if !strings.Contains(out, fmt.Sprintf("writer-%d-%d", i, writesPerWriter-1)) {
+			t.Errorf("expected the last write from writer %d in %v", i, out)
+		}
+	}
+	if got := strings.Count(out, "\"msg\""); got != writers*writesPerWriter {
+		t.Errorf("LogBuffer.String() has %d entries, want %d", got, writers*writesPerWriter)
+	}
+}
+
+func newTestAggregateLogger(buff *LogBuffer) *logrus.Logger {
+	return &logrus.Logger{
+		Out:       buff,
+		Formatter: buff,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logrus.DebugLevel,
+	}
+}
+
+func TestLogBuffer_StructuredEntries(t *testing.T) {
+	buff := NewLogBuffer()
+	logger := newTestAggregateLogger(&buff)
+	logger.WithField("email", "bob@example.com").Info("info-1")
+	logger.Debug("debug-1")
+	logger.WithField("email", "alice@example.com").Error("error-1")
+	logger.Info("info-2")
+
+	entries := buff.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("LogBuffer.Entries() = %d entries, want 4", len(entries))
+	}
+	if entries[0].Message != "info-1" || entries[0].Level != logrus.InfoLevel || entries[0].Fields["email"] != "bob@example.com" {
+		t.Errorf("LogBuffer.Entries()[0] = %v", entries[0])
+	}
+	entries[0].Fields["email"] = "changed"
+	if buff.Entries()[0].Fields["email"] != "bob@example.com" {
+		t.Errorf("LogBuffer.Entries() should return a copy")
+	}
+
+	wantCounts := map[logrus.Level]int{logrus.InfoLevel: 2, logrus.DebugLevel: 1, logrus.ErrorLevel: 1}
+	if got := buff.CountByLevel(); !reflect.DeepEqual(got, wantCounts) {
+		t.Errorf("LogBuffer.CountByLevel() = %v, want %v", got, wantCounts)
+	}
+
+	before := buff.Length()
+	buff.Transform(func(e LogEntry) LogEntry {
+		if _, ok := e.Fields["email"]; ok {
+			e.Fields["email"] = "[REDACTED]"
+		}
+		return e
+	})
+	if strings.Contains(buff.String(), "@example.com") {
+		t.Errorf("LogBuffer.Transform() didn't redact: %v", buff.String())
+	}
+	if buff.Length() == before {
+		t.Errorf("LogBuffer.Transform() didn't recalculate the length")
+	}
+
+	buff.Filter(func(e LogEntry) bool { return e.Level <= logrus.InfoLevel })
+	if got := len(buff.Entries()); got != 3 {
+		t.Errorf("LogBuffer.Filter() left %d entries, want 3", got)
+	}
+	if strings.Contains(buff.String(), "debug-1") {
+		t.Errorf("LogBuffer.Filter() didn't remove debug-1: %v", buff.String())
+	}
+
+	text, err := buff.Render(&logrus.TextFormatter{DisableColors: true})
+	if err != nil {
+		t.Fatal("LogBuffer.Render() error: ", err)
+	}
+	if got := strings.Count(string(text), "\n"); got != 3 {
+		t.Errorf("LogBuffer.Render() = %d lines, want 3: %s", got, text)
+	}
+	if !strings.Contains(string(text), "level=error msg=error-1") {
+		t.Errorf("LogBuffer.Render() = %s", text)
+	}
+}
+
+func TestLogBufferStringIsValidJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		header interface{}
+		banner string
+		field  interface{}
+	}{
+		{"quotes-in-banner", "ok", `say "hi" \ bye`, "ok"},
+		{"percent", "100%s %d", "50%", "%v%n"},
+		{"channel-header", make(chan int), "banner", "ok"},
+		{"nan-header", math.NaN(), "banner", "ok"},
+		{"error-header", errors.New(`bad "thing"`), "banner", "ok"},
+		{"channel-field", "ok", "banner", make(chan int)},
+		{"func-field", "ok", "banner", func() {}},
+		{"inf-field", "ok", "banner", math.Inf(1)},
+		{"invalid-utf8", "\xff\xfe", "\xff", "\xc3\x28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewLogBuffer(WithBanner(true), WithCustomBanner(tt.banner), WithHeader("header", tt.header))
+			logger := &logrus.Logger{Out: &b, Formatter: &b, Hooks: make(logrus.LevelHooks), Level: logrus.DebugLevel}
+			logger.WithField("field", tt.field).Info("hi")
+			s := b.String()
+			if !json.Valid([]byte(s)) {
+				t.Fatalf("LogBuffer.String() isn't valid JSON: %s", s)
+			}
+			var got struct {
+				Entries []map[string]interface{} `json:"entries"`
+			}
+			if err := json.Unmarshal([]byte(s), &got); err != nil || len(got.Entries) != 1 {
+				t.Errorf("LogBuffer.String() entries = %v (%v), want 1 entry", got.Entries, err)
+			}
+		})
+	}
+}
+
+func FuzzLogBufferString(f *testing.F) {
+	f.Add("header", "value", DefaultBanner, "msg", "field", "value", []byte(`{"msg":"hi","level":"warning"}`))
+	f.Add(`"quoted"`, `back\slash`, `"},"x":{`, "%s%d%v", "time", "\x00\x1f", []byte("not json"))
+	f.Add("entries", "\xff", " ", "\n", "level", "", []byte(`{"msg":`))
+	f.Fuzz(func(t *testing.T, headerKey, headerValue, banner, msg, fieldKey, fieldValue string, data []byte) {
+		b := NewLogBuffer(WithBanner(true), WithCustomBanner(banner), WithHeader(headerKey, headerValue))
+		b.StoreHeader(headerKey+"-fields", logrus.Fields{fieldKey: fieldValue})
+		logger := &logrus.Logger{Out: &b, Formatter: &b, Hooks: make(logrus.LevelHooks), Level: logrus.DebugLevel}
+		logger.WithField(fieldKey, fieldValue).Info(msg)
+		_, _ = b.Write(data)
+
+		s := b.String()
+		if !json.Valid([]byte(s)) {
+			t.Fatalf("LogBuffer.String() isn't valid JSON: %q", s)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &got); err != nil {
+			t.Fatalf("LogBuffer.String() can't be decoded: %v", err)
+		}
+		if utf8.ValidString(banner) && len(banner) != 0 && got["banner"] != banner {
+			t.Errorf("banner = %q, want %q", got["banner"], banner)
+		}
+		if utf8.ValidString(headerKey) && utf8.ValidString(headerValue) && headerKey != "entries" && headerKey != "banner" && got[headerKey] != headerValue {
+			t.Errorf("header %q = %q, want %q", headerKey, got[headerKey], headerValue)
+		}
+	})
+}
+
+func TestLogBuffer_EntrySize(t *testing.T) {
+	logged := NewLogBuffer()
+	newTestAggregateLogger(&logged).WithField("user", "bob").Info("signed up")
+	written := NewLogBuffer()
+	if _, err := written.Write([]byte(`{"level":"info","msg":"signed up","user":"bob"}` + "\n")); err != nil {
+		t.Fatal("LogBuffer.Write() error: ", err)
+	}
+	want := len("signed up") + len("user") + len("bob") + 1 // plus the comma
+	if logged.Length() != want || written.Length() != want {
+		t.Errorf("LogBuffer.Length() = %d (logged) and %d (written), want %d", logged.Length(), written.Length(), want)
+	}
+	logged.Transform(func(e LogEntry) LogEntry {
+		e.Fields["user"] = "[REDACTED]"
+		return e
+	})
+	if want = len("signed up") + len("user") + len("[REDACTED]") + 1; logged.Length() != want {
+		t.Errorf("LogBuffer.Length() after Transform() = %d, want %d", logged.Length(), want)
+	}
+}
@@ -0,0 +1,129 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogEntry - a single structured entry stored in the aggregate LogBuffer
+type LogEntry struct {
+	Time    time.Time
+	Level   logrus.Level
+	Message string
+	Fields  logrus.Fields
+
+	size int // the measure() of the entry, which is set when it's stored in a LogBuffer
+}
+
+// jsonEntryFormatter - used to encode LogEntry(s) as JSON, so they look exactly like the ones written by logrus.JSONFormatter
+var jsonEntryFormatter = &logrus.JSONFormatter{}
+
+// newLogEntry - create a LogEntry from a *logrus.Entry.  The fields are copied, since logrus shares them between entries
+func newLogEntry(e *logrus.Entry) LogEntry {
+	fields := make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		fields[k] = v
+	}
+	return LogEntry{
+		Time:    e.Time,
+		Level:   e.Level,
+		Message: e.Message,
+		Fields:  fields,
+	}
+}
+
+// parseLogEntry - create a LogEntry from data written to the LogBuffer.  A JSON object (like the ones written by
+// logrus.JSONFormatter) is split into time, level, msg and fields... anything else just becomes the message
+func parseLogEntry(data []byte) LogEntry {
+	e := LogEntry{
+		Time:   time.Now(),
+		Level:  logrus.InfoLevel,
+		Fields: logrus.Fields{},
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		e.Message = string(data)
+		return e
+	}
+	for k, v := range obj {
+		s, isString := v.(string)
+		switch {
+		case k == logrus.FieldKeyTime && isString:
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				e.Time = t
+				continue
+			}
+		case k == logrus.FieldKeyLevel && isString:
+			if l, err := logrus.ParseLevel(s); err == nil {
+				e.Level = l
+				continue
+			}
+		case k == logrus.FieldKeyMsg && isString:
+			e.Message = s
+			continue
+		}
+		e.Fields[k] = v
+	}
+	return e
+}
+
+// copy - return a copy of the entry which doesn't share its Fields
+func (e LogEntry) copy() LogEntry {
+	fields := make(logrus.Fields, len(e.Fields))
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	e.Fields = fields
+	return e
+}
+
+// logrusEntry - convert the entry back into a *logrus.Entry, so it can be rendered by any logrus.Formatter
+func (e LogEntry) logrusEntry() *logrus.Entry {
+	return &logrus.Entry{
+		Time:    e.Time,
+		Level:   e.Level,
+		Message: e.Message,
+		Data:    e.Fields,
+	}
+}
+
+// MarshalJSON - encode the entry the same way logrus.JSONFormatter does.  Any field that can't be encoded (like a channel
+// or NaN) is encoded as a string instead, so an entry is never lost
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	b, err := jsonEntryFormatter.Format(e.logrusEntry())
+	if err != nil {
+		if b, err = jsonEntryFormatter.Format(e.encodable().logrusEntry()); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.TrimSuffix(b, []byte("\n")), nil
+}
+
+// encodable - a copy of the entry with the fields that can't be encoded as JSON replaced with strings
+func (e LogEntry) encodable() LogEntry {
+	fields := make(logrus.Fields, len(e.Fields))
+	for k, v := range e.Fields {
+		if _, isError := v.(error); !isError {
+			if _, err := json.Marshal(v); err != nil {
+				v = fmt.Sprintf("%v", v)
+			}
+		}
+		fields[k] = v
+	}
+	e.Fields = fields
+	return e
+}
+
+// measure - the size of the entry in a LogBuffer: the length of its message plus the keys and values of its fields.  It
+// doesn't encode the entry, so it's cheap enough to compute for every entry that's stored
+func (e LogEntry) measure() int {
+	n := len(e.Message)
+	for k, v := range e.Fields {
+		n += len(k) + len(fmt.Sprint(v))
+	}
+	return n
+}
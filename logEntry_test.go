@@ -0,0 +1,77 @@
+package ginlogrus
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func Test_parseLogEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantLevel   logrus.Level
+		wantMessage string
+		wantFields  logrus.Fields
+	}{
+		{
+			name:        "json",
+			data:        `{"level":"warning","msg":"hey","time":"2019-02-06T08:24:06-05:00","user":"bob"}`,
+			wantLevel:   logrus.WarnLevel,
+			wantMessage: "hey",
+			wantFields:  logrus.Fields{"user": "bob"},
+		},
+		{
+			name:        "json-bad-level",
+			data:        `{"level":"not-a-level","msg":"hey"}`,
+			wantLevel:   logrus.InfoLevel,
+			wantMessage: "hey",
+			wantFields:  logrus.Fields{"level": "not-a-level"},
+		},
+		{
+			name:        "text",
+			data:        `"msg":"hey-one"`,
+			wantLevel:   logrus.InfoLevel,
+			wantMessage: `"msg":"hey-one"`,
+			wantFields:  logrus.Fields{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLogEntry([]byte(tt.data))
+			if got.Level != tt.wantLevel {
+				t.Errorf("parseLogEntry() Level = %v, want %v", got.Level, tt.wantLevel)
+			}
+			if got.Message != tt.wantMessage {
+				t.Errorf("parseLogEntry() Message = %v, want %v", got.Message, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(got.Fields, tt.wantFields) {
+				t.Errorf("parseLogEntry() Fields = %v, want %v", got.Fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestLogEntry_MarshalJSON(t *testing.T) {
+	e := LogEntry{
+		Time:    time.Date(2019, 2, 6, 13, 24, 6, 0, time.UTC),
+		Level:   logrus.ErrorLevel,
+		Message: "boom",
+		Fields:  logrus.Fields{"err": errors.New("oops"), "msg": "clash"},
+	}
+	got, err := e.MarshalJSON()
+	if err != nil {
+		t.Fatal("LogEntry.MarshalJSON() error: ", err)
+	}
+	want := `{"err":"oops","fields.msg":"clash","level":"error","msg":"boom","time":"2019-02-06T13:24:06Z"}`
+	if string(got) != want {
+		t.Errorf("LogEntry.MarshalJSON() = %v, want %v", string(got), want)
+	}
+	if strings.HasSuffix(string(got), "\n") {
+		t.Errorf("LogEntry.MarshalJSON() shouldn't end with a newline")
+	}
+}
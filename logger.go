@@ -1,18 +1,23 @@
 package ginlogrus
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// aggregateLoggerKey - where the middleware stores the request's aggregate *logrus.Logger in the gin.Context
+	aggregateLoggerKey = "aggregate-logger"
+	// ctxLoggerKey - where the request's *logrus.Entry is stored in the gin.Context
+	ctxLoggerKey = "ctxLogger"
+)
+
 // SetCtxLoggerHeader - if aggregate logging, add header info... otherwise just info log the data passed
 func SetCtxLoggerHeader(c *gin.Context, name string, data interface{}) {
 	logger := GetCtxLogger(c)
-	_, found := c.Get("aggregate-logger")
+	_, found := c.Get(aggregateLoggerKey)
 	if found {
 		logger.Logger.Out.(*LogBuffer).StoreHeader(name, data)
 	}
@@ -23,80 +28,91 @@ func SetCtxLoggerHeader(c *gin.Context, name string, data interface{}) {
 
 // SetCtxLogger - used when you want to set the *logrus.Entry with new logrus.WithFields{} for this request in the gin.Context so it can be used going forward for the request
 func SetCtxLogger(c *gin.Context, logger *logrus.Entry) *logrus.Entry {
-	log, found := c.Get("aggregate-logger")
+	log, found := c.Get(aggregateLoggerKey)
 	if found {
 		logger.Logger = log.(*logrus.Logger)
 		logger = logger.WithFields(logrus.Fields{}) // no need to add additional fields when aggregate logging
 	}
 	if !found {
 		// not aggregate logging, so make sure  to add some needed fields
-		logger = logger.WithFields(logrus.Fields{
-			"requestID": CxtRequestID(c),
-			"method":    c.Request.Method,
-			"path":      c.Request.URL.Path})
+		logger = redactingEntry(c, logger.WithFields(requestFields(c)))
+	}
+	c.Set(ctxLoggerKey, logger)
+	if c.Request != nil {
+		if _, ok := requestContextFrom(c.Request.Context()); ok {
+			// so FromContext(c.Request.Context()) returns it too
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), loggerContextKey{}, logger))
+		}
 	}
-	c.Set("ctxLogger", logger)
 	return logger
 }
 
 // GetCtxLogger - get the *logrus.Entry for this request from the gin.Context
 func GetCtxLogger(c *gin.Context) *logrus.Entry {
-	l, ok := c.Get("ctxLogger")
+	l, ok := c.Get(ctxLoggerKey)
 	if ok {
 		return l.(*logrus.Entry)
 	}
 	var logger *logrus.Entry
-	log, found := c.Get("aggregate-logger")
+	log, found := c.Get(aggregateLoggerKey)
 	if found {
 		logger = logrus.WithFields(logrus.Fields{})
 		logger.Logger = log.(*logrus.Logger)
 	}
 	if !found {
 		// not aggregate logging, so make sure  to add some needed fields
-		logger = logrus.WithFields(logrus.Fields{
-			"requestID": CxtRequestID(c),
-			"method":    c.Request.Method,
-			"path":      c.Request.URL.Path,
-		})
+		logger = redactingEntry(c, logrus.NewEntry(logrus.StandardLogger()).WithFields(requestFields(c)))
 	}
-	c.Set("ctxLogger", logger)
+	c.Set(ctxLoggerKey, logger)
 	return logger
 }
 
-// CxtRequestID - if not already set, then add logrus Field to the entry with the tracing ID for the request.
+// requestFields - the fields needed for every entry when not aggregate logging
+func requestFields(c *gin.Context) logrus.Fields {
+	fields := logrus.Fields{
+		"requestID": CxtRequestID(c),
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.Path,
+	}
+	if path, found := c.Get(loggedPathKey); found {
+		fields["path"] = path
+	}
+	if tc, ok := CxtTraceContext(c); ok {
+		for k, v := range tc.Fields() {
+			fields[k] = v
+		}
+	}
+	if sc, ok := CxtOTelSpanContext(c); ok {
+		for k, v := range otelFields(sc) {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// CxtRequestID - if not already set, then find the tracing ID for the request using the middleware's chain of
+// RequestIDResolver(s) (or the default chain when there's no middleware) and store it in the gin.Context.
 // then return the trace/request id
 func CxtRequestID(c *gin.Context) string {
 	// already setup, so we're done
-	if id, found := c.Get("RequestID"); found == true {
-		return id.(string)
-	}
-
-	// see if we're using github.com/Bose/go-gin-opentracing which will set a span in "tracing-context"
-	if s, foundSpan := c.Get("tracing-context"); foundSpan {
-		span := s.(opentracing.Span)
-		requestID := fmt.Sprintf("%v", span)
-		c.Set("RequestID", requestID)
-		return requestID
+	if id, found := ContextKeyResolver("RequestID").Resolve(c); found {
+		return id
 	}
 
-	// some other process might have stuck it in a header
-	if len(ContextTraceIDField) != 0 {
-		if s, ok := c.Get(ContextTraceIDField); ok {
-			span := s.(opentracing.Span)
-			requestID := fmt.Sprintf("%v", span)
-			c.Set("RequestID", requestID)
-			return requestID
-		}
+	resolvers := defaultRequestIDResolvers(defaultOptions.contextTraceIDField, defaultOptions.traceIDHeader, defaultOptions.idGenerator)
+	if r, found := c.Get(requestIDResolversKey); found {
+		resolvers = r.([]RequestIDResolver)
 	}
-
-	if requestID := c.Request.Header.Get("uber-trace-id"); len(requestID) != 0 {
+	requestID, found := resolveRequestID(c, resolvers)
+	if found {
 		c.Set("RequestID", requestID)
-		return requestID
+		if c.Request != nil {
+			if rc, ok := requestContextFrom(c.Request.Context()); ok {
+				// so ContextRequestID() doesn't need the gin.Context anymore
+				rc.setRequestID(requestID)
+			}
+		}
 	}
-
-	// finally, just create a fake request id...
-	requestID := uuid.New().String()
-	c.Set("RequestID", requestID)
 	return requestID
 }
 
@@ -114,7 +130,7 @@ func GetCxtRequestID(c *gin.Context) string {
 // go func() {
 // 		buff := NewBuffer(logger) // logger is an existing *logrus.Entry
 // 		// do somem work here and write some logs via the logger.  Like logger.Info("hi mom! I'm a go routine that finished after the request")
-// 		fmt.Printf(buff.String()) // this will write the aggregated buffered logs to stdout
+// 		fmt.Print(buff.String()) // this will write the aggregated buffered logs to stdout
 // }()
 //
 func NewBuffer(l *logrus.Entry) *LogBuffer {
@@ -122,11 +138,13 @@ func NewBuffer(l *logrus.Entry) *LogBuffer {
 	if l, ok := l.Logger.Out.(*LogBuffer); ok {
 		CopyHeader(&buff, l)
 		buff.AddBanner = l.AddBanner
+		buff.redactor = l.redactor
+		buff.encoder = l.encoder
 	}
 	// buff.Header = l.Logger.Out.(*ginlogrus.LogBuffer).Header
 	l.Logger = &logrus.Logger{
 		Out:       &buff,
-		Formatter: new(logrus.JSONFormatter),
+		Formatter: &buff,
 		Hooks:     make(logrus.LevelHooks),
 		Level:     logrus.DebugLevel,
 	}
@@ -2,9 +2,11 @@ package ginlogrus
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -41,7 +43,7 @@ func getTestContext(hdr string, v string, withAggregateLogger bool) *gin.Context
 		aggregateLoggingBuff := NewLogBuffer()
 		aggregateRequestLogger := &logrus.Logger{
 			Out:       &aggregateLoggingBuff,
-			Formatter: new(logrus.JSONFormatter),
+			Formatter: &aggregateLoggingBuff,
 			Hooks:     make(logrus.LevelHooks),
 			Level:     logrus.DebugLevel,
 		}
@@ -51,3 +53,24 @@ func getTestContext(hdr string, v string, withAggregateLogger bool) *gin.Context
 	}
 	return c
 }
+
+func TestGetCtxLogger_Concurrency(t *testing.T) {
+	c := getTestContext("boo", "bar", true)
+	logger := GetCtxLogger(c) // get the logger before fanning out, since the gin.Context isn't safe for concurrent Set()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			SetCtxLoggerHeader(c, fmt.Sprintf("goroutine-%d", i), i)
+			logger.WithField("goroutine", i).Info("fan-out")
+		}(i)
+	}
+	wg.Wait()
+
+	aggregate := logger.Logger.Out.(*LogBuffer).String()
+	if got := strings.Count(aggregate, "fan-out"); got != 10 {
+		t.Errorf("expected 10 fan-out entries and got %d in %v", got, aggregate)
+	}
+}
@@ -2,133 +2,348 @@ package ginlogrus
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ContextTraceIDField - used to find the trace id in the gin.Context - optional
-var ContextTraceIDField string
-
 type loggerEntryWithFields interface {
 	WithFields(fields logrus.Fields) *logrus.Entry
 }
 
-// WithTracing returns a gin.HandlerFunc (middleware) that logs requests using logrus.
+// New returns a gin.HandlerFunc (middleware) that logs requests using logrus.
 //
 // Requests with errors are logged using logrus.Error().
 // Requests without errors are logged using logrus.Info().
+// (unless the level is chosen via WithSummaryLevel() or WithStatusLevel())
 //
-// It receives:
-//   1. A logrus.Entry with fields
-//   2. A boolean stating whether to use a BANNER in the log entry
-//   3. A time package format string (e.g. time.RFC3339).
-//   4. A boolean stating whether to use UTC time zone or local.
-//   5. A string to use for Trace ID the Logrus log field.
-//   6. A []byte for the request header that contains the trace id
-//   7. A []byte for "getting" the requestID out of the gin.Context
-//   8. A list of possible ginlogrus.Options to apply
-func WithTracing(
-	logger loggerEntryWithFields,
-	useBanner bool,
-	timeFormat string,
-	utc bool,
-	logrusFieldNameForTraceID string,
-	traceIDHeader []byte,
-	contextTraceIDField []byte,
-	opt ...Option) gin.HandlerFunc {
+// Everything is configured via ginlogrus.Options, and anything not set uses
+// the defaults (logrus.StandardLogger(), no banner, time.RFC3339, UTC,
+// "requestID", "uber-trace-id" and "RequestID")
+func New(opt ...Option) gin.HandlerFunc {
 	opts := defaultOptions
 
 	for _, o := range opt {
 		o(&opts)
 	}
-	if contextTraceIDField != nil {
-		ContextTraceIDField = string(contextTraceIDField)
+	logger := opts.logger
+	useBanner := opts.useBanner
+	resolvers := opts.requestIDResolvers
+	if resolvers == nil {
+		resolvers = defaultRequestIDResolvers(opts.contextTraceIDField, opts.traceIDHeader, opts.idGenerator)
+	}
+	if opts.openTelemetry {
+		resolvers = append([]RequestIDResolver{OTelSpanResolver()}, resolvers...)
+	}
+	var headers *headerCapture
+	if len(opts.requestHeaders) != 0 || len(opts.responseHeaders) != 0 {
+		headers = newHeaderCapture(opts.requestHeaders, opts.responseHeaders, opts.redactedHeaders)
+	}
+	routes := newRouteCapture(opts)
+	skips := newSkipper(opts)
+	var skipped *skipCounter
+	if skips != nil && opts.skippedCountInterval > 0 {
+		skipped = newSkipCounter(opts.skippedCountInterval)
+	}
+	// logSkipped - write the skipped request counts, when they're due
+	logSkipped := func() {
+		fields, due := skipped.due(opts.utc, opts.timeFormat)
+		if !due {
+			return
+		}
+		if !opts.aggregateLogging {
+			logger.WithFields(fields).Info("skipped requests")
+			return
+		}
+		buff := NewLogBuffer(WithBanner(useBanner), WithCustomBanner(opts.banner), WithEncoder(opts.aggregateEncoder))
+		buff.StoreHeader("skipped-requests-info", fields)
+		buff.writeTo(opts.writer)
 	}
 	return func(c *gin.Context) {
 		// var aggregateLoggingBuff strings.Builder
 		// var aggregateLoggingBuff logBuffer
-		aggregateLoggingBuff := NewLogBuffer(WithBanner(useBanner), WithCustomBanner(opts.banner))
+		// before anything can change the status (see RouteTemplate())
+		c.Set(unmatchedRouteKey, isUnmatchedRoute(c))
+		skip := skips != nil && skips.skip(c)
+		spillWriter := opts.writer
+		if skip || opts.tailLogging {
+			// a spill is written before the request is over, so it's only used when the aggregate is always written (the
+			// sampler and the ReducedLoggingFunc can't be asked until the request is over, so they don't stop spills)
+			spillWriter = nil
+		}
+		bufferOpts := []LogBufferOption{WithBanner(useBanner), WithCustomBanner(opts.banner), WithOverflowSpillWriter(spillWriter), WithBufferRedactor(opts.redactor),
+			WithEncoder(opts.aggregateEncoder)}
+		aggregateLoggingBuff := NewLogBuffer(append(bufferOpts, opts.logBufferOptions...)...)
 		aggregateRequestLogger := &logrus.Logger{
 			Out:       &aggregateLoggingBuff,
-			Formatter: new(logrus.JSONFormatter),
+			Formatter: &aggregateLoggingBuff,
 			Hooks:     make(logrus.LevelHooks),
 			Level:     opts.logLevel,
 		}
+		if opts.tailLogging {
+			// always buffer everything, since the entries are only written when the request fails
+			aggregateRequestLogger.Level = logrus.DebugLevel
+		}
 
 		start := time.Now()
 		// some evil middlewares modify this values
 		path := c.Request.URL.Path
+		var routeFields logrus.Fields
+		if routes != nil {
+			routeFields = routes.fields(c)
+			path = routes.path(c)
+		}
 
 		if opts.aggregateLogging {
 			// you have to use this logger for every *logrus.Entry you create
-			c.Set("aggregate-logger", aggregateRequestLogger)
+			c.Set(aggregateLoggerKey, aggregateRequestLogger)
 		}
-		c.Next()
-
-		end := time.Now()
-		latency := end.Sub(start)
-		if utc {
-			end = end.UTC()
+		// so CxtRequestID() uses the same chain for every *logrus.Entry you create
+		c.Set(requestIDResolversKey, resolvers)
+		if opts.redactor != nil {
+			c.Set(redactorKey, opts.redactor)
 		}
-
-		var requestID string
-		// see if we're using github.com/Bose/go-gin-opentracing which will set a span in "tracing-context"
-		if s, foundSpan := c.Get("tracing-context"); foundSpan {
-			span := s.(opentracing.Span)
-			requestID = fmt.Sprintf("%v", span)
+		if path != c.Request.URL.Path {
+			// so the entries of the request (when not aggregate logging) don't have the redacted route params either
+			c.Set(loggedPathKey, path)
+		}
+		if len(opts.requestIDResponseHeader) != 0 {
+			c.Set(requestIDHeaderKey, opts.requestIDResponseHeader)
+			c.Header(opts.requestIDResponseHeader, CxtRequestID(c))
+		}
+		var otelSpanContext trace.SpanContext
+		foundOTelSpan := false
+		if opts.openTelemetry {
+			otelSpanContext, foundOTelSpan = CxtOTelSpanContext(c)
+			if foundOTelSpan && opts.aggregateLogging && opts.openTelemetrySpanEvents {
+				aggregateRequestLogger.Hooks.Add(&otelSpanEventHook{span: trace.SpanFromContext(c.Request.Context()), redactor: opts.redactor})
+			}
+		}
+		var bodies *bodyCapture
+		if opts.bodyCaptureLimit > 0 {
+			bodies = newBodyCapture(c, opts.bodyCaptureLimit, opts.bodyCaptureContentTypes)
+		}
+		traceContext, foundTraceContext := CxtTraceContext(c)
+		if foundTraceContext && opts.traceResponse {
+			// the span-id that's echoed back is logged too, so the caller can find the request's logs
+			traceContext.SpanID = newSpanID()
+			c.Set(traceContextKey, traceContext)
+			c.Header(TraceResponseHeader, traceContext.TraceResponse(traceContext.SpanID))
 		}
-		// check a user defined context field
-		if len(requestID) == 0 && contextTraceIDField != nil {
-			if id, ok := c.Get(string(ContextTraceIDField)); ok {
-				requestID = id.(string)
+		// so code that only has c.Request.Context() can find the request's logger (e.g. FromContext() and NewContextRoundTripper())
+		rc := withRequestContext(c)
+		defer rc.release()
+		if opts.aggregateLogging && spillWriter != nil && aggregateLoggingBuff.overflowPolicy == OverflowSpill {
+			// so partial aggregates written by the OverflowSpill policy can be matched to the request (it's replaced by
+			// the full request summary when the request is over).  A spill can happen after the request is over, so the
+			// gin.Context isn't used
+			method := c.Request.Method
+			aggregateLoggingBuff.beforeSpill = func() {
+				aggregateLoggingBuff.StoreHeader(summaryHeaderKey, logrus.Fields{
+					opts.traceIDFieldName: rc.getRequestID(),
+					"method":              method,
+					"path":                path,
+				})
 			}
 		}
-		// okay.. finally check the request header
-		if len(requestID) == 0 && traceIDHeader != nil {
-			requestID = c.Request.Header.Get(string(traceIDHeader))
+		// flushAggregate - write the aggregate with the request summary (which ended at end).  In the tail mode, only requests which failed get
+		// the entries, and the rest just get the summary (or nothing)
+		flushAggregate := func(fields logrus.Fields, end time.Time, level logrus.Level, failed bool) {
+			flush := aggregateLoggingBuff.Length() > 0 || opts.emptyAggregateEntries
+			if opts.tailLogging && !failed {
+				aggregateLoggingBuff.Filter(func(LogEntry) bool { return false })
+				flush = opts.tailSuccessSummary
+			}
+			if flush {
+				if opts.summaryLevel != nil {
+					summary := make(logrus.Fields, len(fields)+1)
+					for k, v := range fields {
+						summary[k] = v
+					}
+					summary["level"] = level.String()
+					fields = summary
+				}
+				aggregateLoggingBuff.storeSummary(fields, end)
+				aggregateLoggingBuff.writeTo(opts.writer)
+			}
 		}
+		logRequest := func(p *panicInfo) {
+			panicked := p != nil
+			// skipped requests with gin errors or panics are still logged
+			skip := skip && !panicked && len(c.Errors) == 0
+			if skipped != nil {
+				if skip {
+					skipped.add(c)
+				}
+				logSkipped()
+			}
+			if skip {
+				return
+			}
+			end := time.Now()
+			latency := end.Sub(start)
+			if opts.utc {
+				end = end.UTC()
+			}
+
+			requestID := CxtRequestID(c)
+			failed := panicked || len(c.Errors) > 0 || c.Writer.Status() >= http.StatusInternalServerError ||
+				(opts.tailSlowThreshold > 0 && latency >= opts.tailSlowThreshold)
 
-		comment := c.Errors.ByType(gin.ErrorTypePrivate).String()
+			comment := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
-		fields := logrus.Fields{
-			logrusFieldNameForTraceID: requestID,
-			"status":                  c.Writer.Status(),
-			"method":                  c.Request.Method,
-			"path":                    path,
-			"ip":                      c.ClientIP(),
-			"latency-ms":              float64(latency) / float64(time.Millisecond),
-			"user-agent":              c.Request.UserAgent(),
-			"time":                    end.Format(timeFormat),
-			"comment":                 comment,
-		}
-		if len(c.Errors) > 0 {
-			entry := logger.WithFields(fields)
-			// Append error field if this is an erroneous request.
-			entry.Error(c.Errors.String())
-		} else {
-			if gin.Mode() != gin.ReleaseMode && !opts.aggregateLogging {
-				entry := logger.WithFields(fields)
-				if useBanner {
-					entry.Info("[GIN] --------------------------------------------------------------- GinLogrusWithTracing ----------------------------------------------------------------")
-				} else {
-					entry.Info()
-				}
-			}
-			// If aggregate logging is enabled, check if we have entries to log or we are not omitting empty logs
-			if opts.aggregateLogging {
-				//  If we are running structured logging, execute the reduced logging function(default to true)
-				// if we pass the check, check if we have any entries to log or if we are logging empty entries (default to true)
-				executeReduced := opts.reducedLoggingFunc(c)
-				if executeReduced {
-					if aggregateLoggingBuff.Length() > 0 || opts.emptyAggregateEntries {
-						aggregateLoggingBuff.StoreHeader("request-summary-info", fields)
-						fmt.Fprintf(opts.writer, aggregateLoggingBuff.String())
+			fields := logrus.Fields{
+				opts.traceIDFieldName: requestID,
+				"status":              c.Writer.Status(),
+				"method":              c.Request.Method,
+				"path":                path,
+				"ip":                  c.ClientIP(),
+				"latency-ms":          float64(latency) / float64(time.Millisecond),
+				"user-agent":          c.Request.UserAgent(),
+				"time":                end.Format(opts.timeFormat),
+				"comment":             comment,
+			}
+			for k, v := range routeFields {
+				fields[k] = v
+			}
+			if foundTraceContext {
+				for k, v := range traceContext.Fields() {
+					fields[k] = v
+				}
+			}
+			if foundOTelSpan {
+				for k, v := range otelFields(otelSpanContext) {
+					fields[k] = v
+				}
+			}
+			if headers != nil {
+				if h := headers.requestHeaders(c.Request.Header); len(h) != 0 {
+					fields["request-headers"] = h
+				}
+				if h := headers.responseHeaders(c.Writer.Header()); len(h) != 0 {
+					fields["response-headers"] = h
+				}
+			}
+			if bodies != nil {
+				for k, v := range bodies.fields(c) {
+					fields[k] = v
+				}
+			}
+			if panicked {
+				for k, v := range p.fields() {
+					fields[k] = v
+				}
+			}
+			sampled := true
+			if opts.sampler != nil {
+				var rate float64
+				if sampled, rate = opts.sampler.Sample(c, latency); failed {
+					// failed requests (errors, panics, a 5xx status or slow in the tail mode) are always logged
+					sampled, rate = true, 1
+				}
+				fields["sampled"] = sampled
+				fields["sample_rate"] = rate
+			}
+			level := summaryLevel(c, latency, panicked, opts.summaryLevel)
+			entryFields := fields
+			if opts.redactor != nil && (len(c.Errors) > 0 || !opts.aggregateLogging) {
+				// only the entry written by the logger is redacted here, since the aggregate's LogBuffer redacts the
+				// summary when it's stored
+				entryFields = opts.redactor.Fields(fields)
+			}
+			if len(c.Errors) > 0 || (panicked && !opts.aggregateLogging) {
+				entry := logger.WithFields(entryFields)
+				// Append error field if this is an erroneous request.
+				msg := c.Errors.String()
+				if panicked {
+					msg = fmt.Sprintf("panic: %v", p.value)
+				}
+				if opts.redactor != nil {
+					msg = opts.redactor.Message(msg)
+				}
+				entry.Log(level, msg)
+				if opts.aggregateLogging && (opts.tailLogging || panicked) {
+					// the request failed, so the tail mode always has the full entry list (and a panic always has the entries)
+					flushAggregate(fields, end, level, true)
+				}
+			} else {
+				if gin.Mode() != gin.ReleaseMode && !opts.aggregateLogging && sampled {
+					entry := logger.WithFields(entryFields)
+					if useBanner {
+						entry.Log(level, "[GIN] --------------------------------------------------------------- GinLogrusWithTracing ----------------------------------------------------------------")
+					} else {
+						entry.Log(level)
+					}
+				}
+				// If aggregate logging is enabled, check if we have entries to log or we are not omitting empty logs
+				if opts.aggregateLogging {
+					//  If we are running structured logging, execute the reduced logging function(default to true)
+					// if we pass the check, check if we have any entries to log or if we are logging empty entries (default to true)
+					executeReduced := opts.reducedLoggingFunc(c)
+					if (executeReduced && sampled) || panicked {
+						flushAggregate(fields, end, level, failed)
 					}
 				}
 			}
 		}
+		if opts.panicCapture != PanicIgnore || (opts.aggregateLogging && opts.tailLogging) {
+			// capture panics, so the buffered entries are written before the panic carries on (or it's recovered)
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if !c.Writer.Written() {
+					c.Writer.WriteHeader(http.StatusInternalServerError)
+				}
+				logRequest(newPanicInfo(r))
+				if opts.panicCapture != PanicRecover || r == http.ErrAbortHandler {
+					panic(r)
+				}
+				c.Abort()
+				c.Writer.WriteHeaderNow()
+			}()
+		}
+		c.Next()
+		logRequest(nil)
+	}
+}
+
+// WithTracing returns a gin.HandlerFunc (middleware) that logs requests using logrus.
+// It's kept for backwards compatibility and is just a thin wrapper around New()
+//
+// Requests with errors are logged using logrus.Error().
+// Requests without errors are logged using logrus.Info().
+//
+// It receives:
+//  1. A logrus.Entry with fields
+//  2. A boolean stating whether to use a BANNER in the log entry
+//  3. A time package format string (e.g. time.RFC3339).
+//  4. A boolean stating whether to use UTC time zone or local.
+//  5. A string to use for Trace ID the Logrus log field.
+//  6. A []byte for the request header that contains the trace id
+//  7. A []byte for "getting" the requestID out of the gin.Context
+//  8. A list of possible ginlogrus.Options to apply
+func WithTracing(
+	logger loggerEntryWithFields,
+	useBanner bool,
+	timeFormat string,
+	utc bool,
+	logrusFieldNameForTraceID string,
+	traceIDHeader []byte,
+	contextTraceIDField []byte,
+	opt ...Option) gin.HandlerFunc {
+	positional := []Option{
+		WithLogger(logger),
+		WithLogBanner(useBanner),
+		WithTimeFormat(timeFormat),
+		WithUTC(utc),
+		WithTraceIDFieldName(logrusFieldNameForTraceID),
+		WithTraceIDHeader(string(traceIDHeader)),
+		WithContextTraceIDField(string(contextTraceIDField)),
 	}
+	return New(append(positional, opt...)...)
 }
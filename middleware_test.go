@@ -2,6 +2,7 @@ package ginlogrus
 
 import (
 	"bytes"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -288,3 +289,164 @@ func productionLoggingTestFunc(c *gin.Context) bool {
 	}
 	return false
 }
+
+func TestNew(t *testing.T) {
+	is := is.New(t)
+	buff := ""
+	getHandler := func(c *gin.Context) {
+		SetCtxLoggerHeader(c, "new-header-index-name", "this is how you set new header level data")
+
+		logger := GetCtxLogger(c)
+		logger.Info("test-entry-1")
+		c.JSON(200, "Hello world!")
+	}
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	l := bytes.NewBufferString(buff)
+	r := gin.New()
+	r.Use(New(
+		WithLogBanner(true),
+		WithTraceIDFieldName("traceID"),
+		WithTraceIDHeader("x-trace-id"),
+		WithTimeFormat(time.RFC1123),
+		WithAggregateLogging(true),
+		WithWriter(l)))
+	r.GET("/", getHandler)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("x-trace-id", "trace-from-header")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+	is.True(strings.Contains(l.String(), "GinLogrusWithTracing"))
+	is.True(strings.Contains(l.String(), `"traceID":"trace-from-header"`))
+	is.True(strings.Contains(l.String(), "UTC"))
+	is.True(strings.Contains(l.String(), "test-entry-1"))
+}
+
+func TestWithLogBufferOptions(t *testing.T) {
+	is := is.New(t)
+	getHandler := func(c *gin.Context) {
+		logger := GetCtxLogger(c)
+		for i := 0; i < 20; i++ {
+			logger.Infof("test-entry-%d", i)
+		}
+		c.JSON(200, "Hello world!")
+	}
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithWriter(l),
+		WithLogBufferOptions(WithMaxSize(200), WithOverflowPolicy(OverflowDropOldest))))
+	r.GET("/", getHandler)
+	w := performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+	is.True(!strings.Contains(l.String(), `"test-entry-0"`))
+	is.True(strings.Contains(l.String(), `"test-entry-19"`))
+	is.True(strings.Contains(l.String(), `"policy":"drop-oldest"`))
+	is.True(strings.Contains(l.String(), "request-summary-info"))
+}
+
+func TestTraceContext(t *testing.T) {
+	is := is.New(t)
+	getHandler := func(c *gin.Context) {
+		logger := GetCtxLogger(c)
+		logger.Info("test-entry-1")
+		c.JSON(200, "Hello world!")
+	}
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithTraceResponse(true),
+		WithWriter(l)))
+	r.GET("/", getHandler)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TraceStateHeader, "rojo=00f067aa0ba902b7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+	is.True(strings.Contains(l.String(), `"requestID":"4bf92f3577b34da6a3ce929d0e0e4736"`))
+	is.True(strings.Contains(l.String(), `"trace-id":"4bf92f3577b34da6a3ce929d0e0e4736"`))
+	is.True(strings.Contains(l.String(), `"parent-id":"00f067aa0ba902b7"`))
+	is.True(strings.Contains(l.String(), `"trace-sampled":true`))
+	is.True(strings.Contains(l.String(), `"tracestate":"rojo=00f067aa0ba902b7"`))
+	traceResponse, err := ParseTraceParent(w.Header().Get(TraceResponseHeader))
+	is.NoErr(err)
+	is.Equal(traceResponse.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	is.True(traceResponse.ParentID != "00f067aa0ba902b7")
+	// the child-id that's echoed back is the span-id in the summary
+	is.True(strings.Contains(l.String(), `"span-id":"`+traceResponse.ParentID+`"`))
+
+	// no traceparent, so no traceresponse
+	l.Reset()
+	w = performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	is.Equal(w.Header().Get(TraceResponseHeader), "")
+	is.True(!strings.Contains(l.String(), "trace-id"))
+}
+
+func TestWithTailLogging(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		opts        []Option
+		wantEntries bool
+		wantSummary bool
+	}{
+		{name: "success", path: "/", wantSummary: true},
+		{name: "success-nothing", path: "/", opts: []Option{WithTailSuccessSummary(false)}},
+		{name: "5xx", path: "/fail", wantEntries: true, wantSummary: true},
+		{name: "gin-error", path: "/error", wantEntries: true, wantSummary: true},
+		{name: "slow", path: "/slow", opts: []Option{WithTailLogging(5 * time.Millisecond)}, wantEntries: true, wantSummary: true},
+		{name: "panic", path: "/panic", wantEntries: true, wantSummary: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out bytes.Buffer
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(gin.RecoveryWithWriter(&bytes.Buffer{}))
+			opts := append([]Option{
+				WithAggregateLogging(true),
+				WithWriter(&out),
+				WithLogger(logrus.New()),
+				WithLogLevel(logrus.ErrorLevel), // ignored, since the tail mode buffers everything
+				WithTailLogging(0)}, tt.opts...)
+			r.Use(New(opts...))
+			debug := func(c *gin.Context) {
+				SetCtxLoggerHeader(c, "tail-header", "always in the summary")
+				GetCtxLogger(c).Debug("debug-entry")
+			}
+			r.GET("/", debug, func(c *gin.Context) { c.JSON(200, "Hello world!") })
+			r.GET("/fail", debug, func(c *gin.Context) { c.JSON(503, "Hello fail!") })
+			r.GET("/error", debug, func(c *gin.Context) {
+				_ = c.Error(errors.New("boom"))
+				c.JSON(400, "Hello error!")
+			})
+			r.GET("/slow", debug, func(c *gin.Context) {
+				time.Sleep(10 * time.Millisecond)
+				c.JSON(200, "Hello slow!")
+			})
+			r.GET("/panic", debug, func(c *gin.Context) { panic("boom") })
+			performRequest("GET", tt.path, r)
+
+			is.Equal(strings.Contains(out.String(), "debug-entry"), tt.wantEntries)
+			is.Equal(strings.Contains(out.String(), "request-summary-info"), tt.wantSummary)
+			is.Equal(strings.Contains(out.String(), "always in the summary"), tt.wantSummary)
+		})
+	}
+}
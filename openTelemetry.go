@@ -0,0 +1,94 @@
+package ginlogrus
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// OTelTraceIDField - the logrus field name for the OpenTelemetry trace id (the same as the W3C trace context's)
+	OTelTraceIDField = "trace-id"
+	// OTelSpanIDField - the logrus field name for the OpenTelemetry span id (the same as the W3C trace context's)
+	OTelSpanIDField = "span-id"
+)
+
+// CxtOTelSpanContext - get the active OpenTelemetry trace.SpanContext from the request's context.Context
+func CxtOTelSpanContext(c *gin.Context) (trace.SpanContext, bool) {
+	if c.Request == nil {
+		return trace.SpanContext{}, false
+	}
+	sc := trace.SpanContextFromContext(c.Request.Context())
+	return sc, sc.IsValid()
+}
+
+// CxtOTelRequestID - just like CxtRequestID(), except the trace id of the active OpenTelemetry span is used before any
+// of the other places the request id could be found
+func CxtOTelRequestID(c *gin.Context) string {
+	if id, found := ContextKeyResolver("RequestID").Resolve(c); found {
+		return id
+	}
+	if requestID, found := OTelSpanResolver().Resolve(c); found {
+		c.Set("RequestID", requestID)
+		return requestID
+	}
+	return CxtRequestID(c)
+}
+
+// otelFields - the logrus.Fields for the span context, using the standard hex form of the ids
+func otelFields(sc trace.SpanContext) logrus.Fields {
+	return logrus.Fields{
+		OTelTraceIDField: sc.TraceID().String(),
+		OTelSpanIDField:  sc.SpanID().String(),
+	}
+}
+
+// otelSpanEventHook - a logrus.Hook that records every entry as an event on the span.  Span events are exported
+// without going through the LogBuffer, so the hook redacts them itself (when there's a redactor)
+type otelSpanEventHook struct {
+	span     trace.Span
+	redactor *Redactor
+}
+
+// Levels - the hook fires for all levels (the logger's level still applies)
+func (h *otelSpanEventHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - add the entry as a span event
+func (h *otelSpanEventHook) Fire(e *logrus.Entry) error {
+	msg, data := e.Message, e.Data
+	if h.redactor != nil {
+		msg, data = h.redactor.Message(msg), h.redactor.Fields(data)
+	}
+	attrs := make([]attribute.KeyValue, 0, len(data)+1)
+	attrs = append(attrs, attribute.String("log.severity", e.Level.String()))
+	for k, v := range data {
+		attrs = append(attrs, otelAttribute(k, v))
+	}
+	h.span.AddEvent(msg, trace.WithTimestamp(e.Time), trace.WithAttributes(attrs...))
+	return nil
+}
+
+// otelAttribute - convert a logrus field to an attribute.KeyValue
+func otelAttribute(k string, v interface{}) attribute.KeyValue {
+	switch v := v.(type) {
+	case string:
+		return attribute.String(k, v)
+	case bool:
+		return attribute.Bool(k, v)
+	case int:
+		return attribute.Int(k, v)
+	case int64:
+		return attribute.Int64(k, v)
+	case float64:
+		return attribute.Float64(k, v)
+	case error:
+		return attribute.String(k, v.Error())
+	default:
+		return attribute.String(k, fmt.Sprintf("%v", v))
+	}
+}
@@ -0,0 +1,163 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// otelTestMiddleware - start a span for the request, just like an OpenTelemetry gin instrumentation would
+func otelTestMiddleware(tp *sdktrace.TracerProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tp.Tracer("go-gin-logrus-test").Start(c.Request.Context(), c.Request.URL.Path)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func TestWithOpenTelemetry(t *testing.T) {
+	is := is.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var requestID string
+	getHandler := func(c *gin.Context) {
+		requestID = CxtOTelRequestID(c)
+		logger := GetCtxLogger(c)
+		logger.WithField("user", "bob").Info("test-entry-1")
+		logger.Debug("test-entry-2")
+		c.JSON(200, "Hello world!")
+	}
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(otelTestMiddleware(tp))
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithOpenTelemetry(true),
+		WithOpenTelemetrySpanEvents(true),
+		WithWriter(l)))
+	r.GET("/", getHandler)
+	w := performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+
+	spans := recorder.Ended()
+	is.Equal(len(spans), 1)
+	traceID := spans[0].SpanContext().TraceID().String()
+	spanID := spans[0].SpanContext().SpanID().String()
+	is.Equal(requestID, traceID)
+	is.True(strings.Contains(l.String(), `"requestID":"`+traceID+`"`))
+	is.True(strings.Contains(l.String(), `"trace-id":"`+traceID+`"`))
+	is.True(strings.Contains(l.String(), `"span-id":"`+spanID+`"`))
+
+	events := spans[0].Events()
+	is.Equal(len(events), 2)
+	is.Equal(events[0].Name, "test-entry-1")
+	is.True(hasAttribute(events[0].Attributes, attribute.String("user", "bob")))
+	is.True(hasAttribute(events[0].Attributes, attribute.String("log.severity", "info")))
+	is.Equal(events[1].Name, "test-entry-2")
+}
+
+func TestWithOpenTelemetrySpanEventsRedacted(t *testing.T) {
+	is := is.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(otelTestMiddleware(tp))
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithOpenTelemetry(true),
+		WithOpenTelemetrySpanEvents(true),
+		WithRedactor(NewRedactor(RedactKey("password", RedactDrop), RedactEmails(RedactMask))),
+		WithWriter(bytes.NewBufferString(""))))
+	r.GET("/", func(c *gin.Context) {
+		GetCtxLogger(c).WithFields(logrus.Fields{"password": "secret", "to": "bob@example.com"}).Info("sent to bob@example.com")
+		c.JSON(200, "Hello world!")
+	})
+	w := performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+
+	spans := recorder.Ended()
+	is.Equal(len(spans), 1)
+	events := spans[0].Events()
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Name, "sent to "+RedactedValue)
+	is.True(hasAttribute(events[0].Attributes, attribute.String("to", RedactedValue)))
+	for _, a := range events[0].Attributes {
+		is.True(a.Key != "password") // dropped fields aren't span attributes
+	}
+}
+
+func TestCxtOTelRequestID(t *testing.T) {
+	is := is.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// no span, so it's just like CxtRequestID()
+	c := getTestContext("uber-trace-id", "uber-id", false)
+	is.Equal(CxtOTelRequestID(c), "uber-id")
+
+	c = getTestContext("uber-trace-id", "uber-id", false)
+	ctx, span := tp.Tracer("go-gin-logrus-test").Start(c.Request.Context(), "test")
+	c.Request = c.Request.WithContext(ctx)
+	is.Equal(CxtOTelRequestID(c), span.SpanContext().TraceID().String())
+	fields := GetCtxLogger(c).Data
+	is.Equal(fields[OTelTraceIDField], span.SpanContext().TraceID().String())
+	is.Equal(fields[OTelSpanIDField], span.SpanContext().SpanID().String())
+	span.End()
+}
+
+func TestWithOpenTelemetryAndTraceParent(t *testing.T) {
+	is := is.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	gin.SetMode(gin.DebugMode)
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(otelTestMiddleware(tp))
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithOpenTelemetry(true),
+		WithWriter(l)))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(200, "Hello world!")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+
+	// both use the same field names, so there's one trace-id and span-id (the server's span) in the summary
+	spans := recorder.Ended()
+	is.Equal(len(spans), 1)
+	is.Equal(strings.Count(l.String(), `"trace-id":`), 1)
+	is.Equal(strings.Count(l.String(), `"span-id":`), 1)
+	is.True(strings.Contains(l.String(), `"span-id":"`+spans[0].SpanContext().SpanID().String()+`"`))
+	is.True(strings.Contains(l.String(), `"parent-id":"00f067aa0ba902b7"`))
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
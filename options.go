@@ -3,12 +3,14 @@ package ginlogrus
 import (
 	"io"
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-// Option - define options for WithTracing()
+// Option - define options for New() and WithTracing()
 type Option func(*options)
 
 // Function definition for reduced logging. The return value of this function
@@ -16,22 +18,120 @@ type Option func(*options)
 type ReducedLoggingFunc func(c *gin.Context) bool
 
 type options struct {
-	aggregateLogging      bool
-	logLevel              logrus.Level
-	emptyAggregateEntries bool
-	reducedLoggingFunc    ReducedLoggingFunc
-	writer                io.Writer
-	banner                string
+	logger                  loggerEntryWithFields
+	useBanner               bool
+	timeFormat              string
+	utc                     bool
+	traceIDFieldName        string
+	traceIDHeader           string
+	contextTraceIDField     string
+	aggregateLogging        bool
+	logLevel                logrus.Level
+	emptyAggregateEntries   bool
+	reducedLoggingFunc      ReducedLoggingFunc
+	writer                  io.Writer
+	banner                  string
+	logBufferOptions        []LogBufferOption
+	traceResponse           bool
+	openTelemetry           bool
+	openTelemetrySpanEvents bool
+	requestIDResolvers      []RequestIDResolver
+	idGenerator             IDGenerator
+	requestIDResponseHeader string
+	bodyCaptureLimit        int
+	bodyCaptureContentTypes []string
+	requestHeaders          []string
+	responseHeaders         []string
+	redactedHeaders         []string
+	redactor                *Redactor
+	sampler                 Sampler
+	tailLogging             bool
+	tailSlowThreshold       time.Duration
+	tailSuccessSummary      bool
+	panicCapture            PanicCapture
+	summaryLevel            SummaryLevelFunc
+	skipPaths               []string
+	skipPathPrefixes        []string
+	skipPathRegexps         []*regexp.Regexp
+	skipRoutes              []string
+	skipMethods             []string
+	skippedCountInterval    time.Duration
+	routeTemplate           bool
+	routeParams             bool
+	redactedRouteParams     []string
+	handlerName             bool
+	aggregateEncoder        AggregateEncoder
 }
 
-// defaultOptions - some defs options to NewJWTCache()
+// defaultOptions - some defs options to New()
 var defaultOptions = options{
-	aggregateLogging:      false,
-	logLevel:              logrus.DebugLevel,
-	emptyAggregateEntries: true,
-	reducedLoggingFunc:    func(c *gin.Context) bool { return true },
-	writer:                os.Stdout,
-	banner:                DefaultBanner,
+	logger:                  logrus.StandardLogger(),
+	useBanner:               false,
+	timeFormat:              time.RFC3339,
+	utc:                     true,
+	traceIDFieldName:        "requestID",
+	traceIDHeader:           "uber-trace-id",
+	contextTraceIDField:     "RequestID",
+	aggregateLogging:        false,
+	logLevel:                logrus.DebugLevel,
+	emptyAggregateEntries:   true,
+	reducedLoggingFunc:      func(c *gin.Context) bool { return true },
+	writer:                  os.Stdout,
+	banner:                  DefaultBanner,
+	idGenerator:             UUIDGenerator,
+	bodyCaptureContentTypes: DefaultBodyCaptureContentTypes,
+	tailSuccessSummary:      true,
+}
+
+// WithLogger - define an Option func for passing in the logger used to write the request summary, the default is logrus.StandardLogger()
+func WithLogger(logger loggerEntryWithFields) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLogBanner - define an Option func for passing in an optional useBanner
+func WithLogBanner(a bool) Option {
+	return func(o *options) {
+		o.useBanner = a
+	}
+}
+
+// WithTimeFormat - define an Option func for passing in the time package format string (e.g. time.RFC3339) used for the request summary time
+func WithTimeFormat(f string) Option {
+	return func(o *options) {
+		o.timeFormat = f
+	}
+}
+
+// WithUTC - define an Option func for passing in whether to use the UTC time zone or local
+func WithUTC(a bool) Option {
+	return func(o *options) {
+		o.utc = a
+	}
+}
+
+// WithTraceIDFieldName - define an Option func for passing in the logrus field name used for the Trace ID
+func WithTraceIDFieldName(name string) Option {
+	return func(o *options) {
+		o.traceIDFieldName = name
+	}
+}
+
+// WithTraceIDHeader - define an Option func for passing in the request header that contains the trace id, which is used by the
+// default chain of RequestIDResolver(s).  An empty string disables the header lookup
+func WithTraceIDHeader(h string) Option {
+	return func(o *options) {
+		o.traceIDHeader = h
+	}
+}
+
+// WithContextTraceIDField - define an Option func for passing in the gin.Context key for "getting" the requestID, which is used by the
+// default chain of RequestIDResolver(s).  An empty string disables the lookup
+func WithContextTraceIDField(f string) Option {
+	return func(o *options) {
+		o.contextTraceIDField = f
+	}
 }
 
 // WithAggregateLogging - define an Option func for passing in an optional aggregateLogging
@@ -76,3 +176,250 @@ func WithLogCustomBanner(b string) Option {
 		o.banner = b
 	}
 }
+
+// WithLogBufferOptions allows users to pass LogBufferOptions (e.g. WithMaxSize() or WithOverflowPolicy()) to the aggregate
+// logging buffer created for every request.  Partial aggregates written by the OverflowSpill policy go to the middleware's writer
+// with the request id, method and path in "request-summary-info".  Skipped requests and the tail mode don't spill (the entries
+// that don't fit are dropped instead), but the sampler and the ReducedLoggingFunc are only asked when the request is over,
+// so they don't stop spills
+func WithLogBufferOptions(opt ...LogBufferOption) Option {
+	return func(o *options) {
+		o.logBufferOptions = append(o.logBufferOptions, opt...)
+	}
+}
+
+// WithTraceResponse - define an Option func for echoing back a W3C traceresponse header, when the request has a valid traceparent header.
+// Its child-id is the request's span-id, which is logged as "span-id"
+func WithTraceResponse(a bool) Option {
+	return func(o *options) {
+		o.traceResponse = a
+	}
+}
+
+// WithOpenTelemetry - define an Option func for using the active OpenTelemetry span from the request's context.Context.
+// Its trace id is used as the request id, and the trace-id and span-id are added to the request summary
+func WithOpenTelemetry(a bool) Option {
+	return func(o *options) {
+		o.openTelemetry = a
+	}
+}
+
+// WithOpenTelemetrySpanEvents - define an Option func for also recording every aggregated log entry as an event on the
+// active OpenTelemetry span.  It's only used with WithOpenTelemetry(true) and WithAggregateLogging(true)
+func WithOpenTelemetrySpanEvents(a bool) Option {
+	return func(o *options) {
+		o.openTelemetrySpanEvents = a
+	}
+}
+
+// WithRequestIDResolvers - define an Option func for passing in an ordered chain of RequestIDResolver(s), which replaces the default
+// chain (the go-gin-opentracing span, WithContextTraceIDField(), WithTraceIDHeader(), the W3C traceparent and WithIDGenerator()).
+// The same chain is used by the middleware and CxtRequestID()
+func WithRequestIDResolvers(r ...RequestIDResolver) Option {
+	return func(o *options) {
+		o.requestIDResolvers = r
+	}
+}
+
+// WithIDGenerator - define an Option func for passing in the IDGenerator used by the default chain of RequestIDResolver(s), when
+// the request id isn't found anywhere else.  The default is UUIDGenerator
+func WithIDGenerator(g IDGenerator) Option {
+	return func(o *options) {
+		o.idGenerator = g
+	}
+}
+
+// WithRequestIDResponseHeader - define an Option func for passing in a response header (e.g. "X-Request-ID") for echoing back the
+// request id.  The request id is resolved before the handler is called, so the header is written before the body.  It's also the
+// header used by InjectRequestHeaders()
+func WithRequestIDResponseHeader(h string) Option {
+	return func(o *options) {
+		o.requestIDResponseHeader = h
+	}
+}
+
+// WithBodyCapture - define an Option func for capturing up to maxBytes of the request and response bodies in the request
+// summary.  Bodies are only captured for error responses (status >= 400) or routes using CaptureBodies().  The default is 0,
+// which disables capturing
+func WithBodyCapture(maxBytes int) Option {
+	return func(o *options) {
+		o.bodyCaptureLimit = maxBytes
+	}
+}
+
+// WithBodyCaptureContentTypes - define an Option func for passing in the content types (or prefixes like "text/") of the
+// bodies that are captured, the default is DefaultBodyCaptureContentTypes
+func WithBodyCaptureContentTypes(types ...string) Option {
+	return func(o *options) {
+		o.bodyCaptureContentTypes = types
+	}
+}
+
+// WithRequestHeaders - define an Option func for passing in an allowlist of request headers (e.g. "Content-Type" or
+// "X-Forwarded-For") to add to the request summary as "request-headers".  Secrets are always redacted (see WithRedactedHeaders())
+func WithRequestHeaders(names ...string) Option {
+	return func(o *options) {
+		o.requestHeaders = append(o.requestHeaders, names...)
+	}
+}
+
+// WithResponseHeaders - define an Option func for passing in an allowlist of response headers to add to the request summary as
+// "response-headers".  Secrets are always redacted (see WithRedactedHeaders())
+func WithResponseHeaders(names ...string) Option {
+	return func(o *options) {
+		o.responseHeaders = append(o.responseHeaders, names...)
+	}
+}
+
+// WithRedactedHeaders - define an Option func for passing in more headers to redact, when they're in the request or response
+// header allowlist.  They're added to DefaultRedactedHeaders, which are always redacted
+func WithRedactedHeaders(names ...string) Option {
+	return func(o *options) {
+		o.redactedHeaders = append(o.redactedHeaders, names...)
+	}
+}
+
+// WithRedactor - define an Option func for redacting PII (see NewRedactor()) from every entry and header in the aggregate, the
+// request summary and the entries from GetCtxLogger() when not aggregate logging
+func WithRedactor(r *Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
+// WithSampler - define an Option func for sampling the requests that are logged (e.g. PercentageSampler(0.1)).  Requests
+// that failed (gin errors, panics, a 5xx status, or slow ones in the tail mode) are always logged.  The sampling
+// decision is added to the request summary as "sampled" and "sample_rate"
+func WithSampler(s Sampler) Option {
+	return func(o *options) {
+		o.sampler = s
+	}
+}
+
+// WithTailLogging - define an Option func for the tail mode, which always buffers at the Debug level (ignoring WithLogLevel()),
+// but only writes the entries when the request fails: gin errors, a 5xx status, a panic or a latency of at least slow (0 disables
+// it).  Requests that don't fail just get the request summary (see WithTailSuccessSummary()).  It's only used with
+// WithAggregateLogging(true)
+func WithTailLogging(slow time.Duration) Option {
+	return func(o *options) {
+		o.tailLogging = true
+		o.tailSlowThreshold = slow
+	}
+}
+
+// WithTailSuccessSummary - define an Option func for writing the request summary (without entries) for requests that don't
+// fail in the tail mode, the default is true.  When it's false, nothing is written for them
+func WithTailSuccessSummary(a bool) Option {
+	return func(o *options) {
+		o.tailSuccessSummary = a
+	}
+}
+
+// WithPanicCapture - define an Option func for capturing handler panics: the panic value and a trimmed stack trace are added
+// to the request summary as "panic" and "stack", the buffered entries are written and the status is set to 500.  Then the panic
+// carries on (PanicObserve) or it's recovered (PanicRecover).  The default is PanicIgnore
+func WithPanicCapture(p PanicCapture) Option {
+	return func(o *options) {
+		o.panicCapture = p
+	}
+}
+
+// WithSummaryLevel - define an Option func for choosing the logrus.Level of the request summary (e.g. StatusLevel()), which is
+// also added to the aggregate's request summary as "level".  The default is Error for gin errors and Info for everything else
+func WithSummaryLevel(f SummaryLevelFunc) Option {
+	return func(o *options) {
+		o.summaryLevel = f
+	}
+}
+
+// WithStatusLevel - define an Option func for choosing the logrus.Level of the request summary by its status and latency: Error
+// for gin errors and 5xx responses, Warn for 4xx responses and requests slower than slow (0 disables it) and Info for everything else
+func WithStatusLevel(slow time.Duration) Option {
+	return WithSummaryLevel(StatusLevel(slow))
+}
+
+// WithSkipPaths - define an Option func for passing in exact paths (e.g. "/healthz" or "/metrics") which aren't logged.  Skipped
+// requests with gin errors or panics are still logged
+func WithSkipPaths(paths ...string) Option {
+	return func(o *options) {
+		o.skipPaths = append(o.skipPaths, paths...)
+	}
+}
+
+// WithSkipPathPrefixes - define an Option func for passing in path prefixes (e.g. "/debug/") which aren't logged
+func WithSkipPathPrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.skipPathPrefixes = append(o.skipPathPrefixes, prefixes...)
+	}
+}
+
+// WithSkipPathRegexps - define an Option func for passing in regexps of paths which aren't logged
+func WithSkipPathRegexps(res ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.skipPathRegexps = append(o.skipPathRegexps, res...)
+	}
+}
+
+// WithSkipRoutes - define an Option func for passing in gin route templates (e.g. "/static/*filepath") which aren't logged.
+// See RouteTemplate()
+func WithSkipRoutes(routes ...string) Option {
+	return func(o *options) {
+		o.skipRoutes = append(o.skipRoutes, routes...)
+	}
+}
+
+// WithSkipMethods - define an Option func for passing in HTTP methods (e.g. "OPTIONS" or "HEAD") which aren't logged
+func WithSkipMethods(methods ...string) Option {
+	return func(o *options) {
+		o.skipMethods = append(o.skipMethods, methods...)
+	}
+}
+
+// WithSkippedRequestCounter - define an Option func for counting the skipped requests by method and route template, so
+// they're still visible.  The counts are written by the first request after every interval, as a "skipped-requests-info"
+// aggregate (or an Info entry when not aggregate logging).  The default is 0, which disables counting
+func WithSkippedRequestCounter(interval time.Duration) Option {
+	return func(o *options) {
+		o.skippedCountInterval = interval
+	}
+}
+
+// WithRouteTemplate - define an Option func for adding the matched gin route template (e.g. "/users/:id") to the request
+// summary as "route", so requests for the same endpoint can be grouped.  See RouteTemplate()
+func WithRouteTemplate(a bool) Option {
+	return func(o *options) {
+		o.routeTemplate = a
+	}
+}
+
+// WithRouteParams - define an Option func for adding the route parameters (e.g. {"id": "123"}) to the request summary as
+// "route-params".  Use WithRedactedRouteParams() or a RedactKey() rule to redact them
+func WithRouteParams(a bool) Option {
+	return func(o *options) {
+		o.routeParams = a
+	}
+}
+
+// WithRedactedRouteParams - define an Option func for passing in the names of route parameters (e.g. "email") whose values
+// are replaced with RedactedValue in "route-params" and in the logged "path"
+func WithRedactedRouteParams(names ...string) Option {
+	return func(o *options) {
+		o.redactedRouteParams = append(o.redactedRouteParams, names...)
+	}
+}
+
+// WithHandlerName - define an Option func for adding the name of the route's handler (c.HandlerName()) to the request
+// summary as "handler"
+func WithHandlerName(a bool) Option {
+	return func(o *options) {
+		o.handlerName = a
+	}
+}
+
+// WithAggregateEncoder - define an Option func for passing in the AggregateEncoder used to write the aggregate (e.g.
+// ECSEncoder(), GCPEncoder(), LogfmtEncoder() or TextEncoder()), the default is JSONEncoder()
+func WithAggregateEncoder(e AggregateEncoder) Option {
+	return func(o *options) {
+		o.aggregateEncoder = e
+	}
+}
@@ -1,8 +1,10 @@
 package ginlogrus
 
 import (
-	"github.com/sirupsen/logrus"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestWithAggregateLogging(t *testing.T) {
@@ -64,4 +66,78 @@ func TestWithLogLevel(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestWithTimeFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "rfc3339", want: time.RFC3339},
+		{name: "kitchen", want: time.Kitchen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions
+			f := WithTimeFormat(tt.want)
+			f(&opts)
+			if opts.timeFormat != tt.want {
+				t.Errorf("WithTimeFormat() = %v, want %v", opts.timeFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTraceIDHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "uber", want: "uber-trace-id"},
+		{name: "disabled", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions
+			f := WithTraceIDHeader(tt.want)
+			f(&opts)
+			if opts.traceIDHeader != tt.want {
+				t.Errorf("WithTraceIDHeader() = %v, want %v", opts.traceIDHeader, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithContextTraceIDField(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "RequestID", want: "RequestID"},
+		{name: "disabled", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions
+			f := WithContextTraceIDField(tt.want)
+			f(&opts)
+			if opts.contextTraceIDField != tt.want {
+				t.Errorf("WithContextTraceIDField() = %v, want %v", opts.contextTraceIDField, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithBodyCapture(t *testing.T) {
+	opts := defaultOptions
+	WithBodyCapture(1024)(&opts)
+	WithBodyCaptureContentTypes("text/")(&opts)
+	if opts.bodyCaptureLimit != 1024 {
+		t.Errorf("WithBodyCapture() = %v, want %v", opts.bodyCaptureLimit, 1024)
+	}
+	if len(opts.bodyCaptureContentTypes) != 1 || opts.bodyCaptureContentTypes[0] != "text/" {
+		t.Errorf("WithBodyCaptureContentTypes() = %v, want [text/]", opts.bodyCaptureContentTypes)
+	}
+	if len(defaultOptions.bodyCaptureContentTypes) != len(DefaultBodyCaptureContentTypes) {
+		t.Errorf("defaultOptions.bodyCaptureContentTypes = %v", defaultOptions.bodyCaptureContentTypes)
+	}
+}
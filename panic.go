@@ -0,0 +1,68 @@
+package ginlogrus
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PanicCapture - what the middleware does when a handler panics
+type PanicCapture int
+
+const (
+	// PanicIgnore - the default: panics aren't captured (except by the tail mode), and the buffered entries are lost
+	PanicIgnore PanicCapture = iota
+	// PanicObserve - capture the panic in the request summary and write the buffered entries, then carry on panicking
+	// so a recovery middleware (like gin.Recovery()) still handles it
+	PanicObserve
+	// PanicRecover - capture the panic in the request summary, write the buffered entries and recover with a 500 status
+	PanicRecover
+)
+
+// maxPanicStackFrames - the stack trace in the request summary is trimmed to this many frames
+const maxPanicStackFrames = 32
+
+// panicInfo - a captured panic
+type panicInfo struct {
+	value interface{}
+	stack []string
+}
+
+// newPanicInfo - capture the panic value and the stack of the panicking go routine.  It must be called by the
+// deferred func that recovered the panic
+func newPanicInfo(r interface{}) *panicInfo {
+	return &panicInfo{value: r, stack: panicStack()}
+}
+
+// fields - the panic value and stack for the request summary
+func (p *panicInfo) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"panic": fmt.Sprintf("%v", p.value),
+		"stack": p.stack,
+	}
+}
+
+// panicStack - the stack trace from where the panic happened, trimmed to the panicking frames (not the runtime and
+// the deferred funcs that recovered it) and maxPanicStackFrames
+func panicStack() []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []string
+	panicking := false
+	for {
+		f, more := frames.Next()
+		switch {
+		case !panicking:
+			// skip everything until the runtime's panic frames
+			panicking = f.Function == "runtime.gopanic" || strings.HasPrefix(f.Function, "runtime.panic")
+		case strings.HasPrefix(f.Function, "runtime."):
+		default:
+			stack = append(stack, fmt.Sprintf("%s (%s:%d)", f.Function, filepath.Base(f.File), f.Line))
+		}
+		if !more || len(stack) == maxPanicStackFrames {
+			return stack
+		}
+	}
+}
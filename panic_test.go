@@ -0,0 +1,88 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+func panickingHandler(c *gin.Context) {
+	GetCtxLogger(c).Info("before-the-panic")
+	panic("boom")
+}
+
+func TestWithPanicCapture(t *testing.T) {
+	tests := []struct {
+		name         string
+		capture      PanicCapture
+		withRecovery bool
+		wantCaptured bool
+	}{
+		{name: "ignore", capture: PanicIgnore, withRecovery: true},
+		{name: "observe", capture: PanicObserve, withRecovery: true, wantCaptured: true},
+		{name: "recover", capture: PanicRecover, wantCaptured: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out bytes.Buffer
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			if tt.withRecovery {
+				r.Use(gin.RecoveryWithWriter(&bytes.Buffer{}))
+			}
+			r.Use(New(
+				WithAggregateLogging(true),
+				WithWriter(&out),
+				WithPanicCapture(tt.capture),
+				WithReducedLoggingFunc(func(c *gin.Context) bool { return false }))) // panics are always written
+			r.GET("/", panickingHandler)
+			w := performRequest("GET", "/", r)
+			is.Equal(w.Code, http.StatusInternalServerError)
+			if !tt.wantCaptured {
+				is.Equal(out.Len(), 0)
+				return
+			}
+
+			var aggregate struct {
+				Entries []map[string]interface{} `json:"entries"`
+				Summary struct {
+					Status int      `json:"status"`
+					Panic  string   `json:"panic"`
+					Stack  []string `json:"stack"`
+				} `json:"request-summary-info"`
+			}
+			is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+			is.Equal(len(aggregate.Entries), 1)
+			is.Equal(aggregate.Entries[0]["msg"], "before-the-panic")
+			is.Equal(aggregate.Summary.Status, http.StatusInternalServerError)
+			is.Equal(aggregate.Summary.Panic, "boom")
+			is.True(len(aggregate.Summary.Stack) > 0 && len(aggregate.Summary.Stack) <= maxPanicStackFrames)
+			is.True(strings.Contains(aggregate.Summary.Stack[0], "panickingHandler"))
+		})
+	}
+}
+
+func TestWithPanicCapture_NotAggregate(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &out
+	logger.Formatter = &logrus.JSONFormatter{}
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithLogger(logger), WithPanicCapture(PanicRecover)))
+	r.GET("/", panickingHandler)
+	w := performRequest("GET", "/", r)
+	is.Equal(w.Code, http.StatusInternalServerError)
+
+	is.True(strings.Contains(out.String(), `"level":"error"`))
+	is.True(strings.Contains(out.String(), `"msg":"panic: boom"`))
+	is.True(strings.Contains(out.String(), `"status":500`))
+}
@@ -0,0 +1,44 @@
+package ginlogrus
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	opentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// DefaultRequestIDHeader - the header used to propagate the request id, when WithRequestIDResponseHeader() isn't used
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// requestIDHeaderKey - where the middleware stores the request id header name in the gin.Context
+const requestIDHeaderKey = "request-id-header"
+
+// InjectRequestHeaders - inject the request id and trace headers for the request into an outbound *http.Request, so
+// downstream services can be matched to the aggregate logs.  The request id goes in the header from
+// WithRequestIDResponseHeader() (or DefaultRequestIDHeader).  Then the trace headers come from the active OpenTelemetry
+// span, the W3C traceparent/tracestate or the go-gin-opentracing span (whichever are found)
+func InjectRequestHeaders(c *gin.Context, req *http.Request) {
+	header := DefaultRequestIDHeader
+	if h, found := c.Get(requestIDHeaderKey); found {
+		header = h.(string)
+	}
+	if requestID := CxtRequestID(c); len(requestID) != 0 {
+		req.Header.Set(header, requestID)
+	}
+
+	if _, ok := CxtOTelSpanContext(c); ok {
+		propagation.TraceContext{}.Inject(c.Request.Context(), propagation.HeaderCarrier(req.Header))
+	} else if tc, ok := CxtTraceContext(c); ok {
+		req.Header.Set(TraceParentHeader, tc.TraceParent())
+		if len(tc.TraceState) != 0 {
+			req.Header.Set(TraceStateHeader, tc.TraceState)
+		}
+	}
+
+	if s, found := c.Get("tracing-context"); found {
+		if span, ok := s.(opentracing.Span); ok {
+			_ = span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+		}
+	}
+}
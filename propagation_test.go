@@ -0,0 +1,79 @@
+package ginlogrus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestWithRequestIDResponseHeader(t *testing.T) {
+	is := is.New(t)
+	var outbound *http.Request
+	getHandler := func(c *gin.Context) {
+		outbound, _ = http.NewRequest("GET", "http://downstream.example.com/", nil)
+		InjectRequestHeaders(c, outbound)
+		c.JSON(200, "Hello world!")
+	}
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	r := gin.New()
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithWriter(&discardWriter{}),
+		WithRequestIDResponseHeader("X-Correlation-ID")))
+	r.GET("/", getHandler)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TraceStateHeader, "rojo=00f067aa0ba902b7")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	is.Equal(200, w.Code)
+	is.Equal(w.Header().Get("X-Correlation-ID"), "4bf92f3577b34da6a3ce929d0e0e4736")
+	is.Equal(outbound.Header.Get("X-Correlation-ID"), "4bf92f3577b34da6a3ce929d0e0e4736")
+	is.Equal(outbound.Header.Get(TraceParentHeader), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	is.Equal(outbound.Header.Get(TraceStateHeader), "rojo=00f067aa0ba902b7")
+}
+
+func TestInjectRequestHeaders(t *testing.T) {
+	is := is.New(t)
+
+	// no middleware, so the default header is used
+	c := getTestContext("uber-trace-id", "uber-id", false)
+	outbound, _ := http.NewRequest("GET", "http://downstream.example.com/", nil)
+	InjectRequestHeaders(c, outbound)
+	is.Equal(outbound.Header.Get(DefaultRequestIDHeader), "uber-id")
+	is.Equal(outbound.Header.Get(TraceParentHeader), "")
+
+	// an OpenTelemetry span wins over the incoming traceparent
+	tp := sdktrace.NewTracerProvider()
+	c = getTestContext(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false)
+	ctx, span := tp.Tracer("go-gin-logrus-test").Start(c.Request.Context(), "test")
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+	outbound, _ = http.NewRequest("GET", "http://downstream.example.com/", nil)
+	InjectRequestHeaders(c, outbound)
+	tc, err := ParseTraceParent(outbound.Header.Get(TraceParentHeader))
+	is.NoErr(err)
+	is.Equal(tc.TraceID, span.SpanContext().TraceID().String())
+	is.Equal(tc.ParentID, span.SpanContext().SpanID().String())
+
+	// go-gin-opentracing span
+	tracer := mocktracer.New()
+	c = getTestContext("boo", "bar", false)
+	c.Set("tracing-context", tracer.StartSpan("test"))
+	outbound, _ = http.NewRequest("GET", "http://downstream.example.com/", nil)
+	InjectRequestHeaders(c, outbound)
+	is.True(len(outbound.Header.Get("Mockpfx-Ids-Traceid")) != 0)
+	is.True(len(outbound.Header.Get(DefaultRequestIDHeader)) != 0)
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
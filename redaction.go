@@ -0,0 +1,331 @@
+package ginlogrus
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// redactorKey - where the middleware stores the *Redactor in the gin.Context
+const redactorKey = "redactor"
+
+// RedactionStrategy - how a redacted value is replaced.  It returns the replacement, or false when the value is dropped
+type RedactionStrategy func(value string) (string, bool)
+
+// RedactMask - replace the value with RedactedValue
+var RedactMask RedactionStrategy = func(string) (string, bool) {
+	return RedactedValue, true
+}
+
+// RedactDrop - drop the field (or remove the value from the message)
+var RedactDrop RedactionStrategy = func(string) (string, bool) {
+	return "", false
+}
+
+// RedactHash - replace the value with a salted hash (HMAC-SHA256), so the same value can still be matched across
+// entries without being logged
+func RedactHash(salt string) RedactionStrategy {
+	return func(value string) (string, bool) {
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write([]byte(value))
+		return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16], true
+	}
+}
+
+// RedactionRule - a rule for the Redactor, which matches either a field key or a value
+type RedactionRule struct {
+	key      string
+	value    *regexp.Regexp
+	valid    func(match string) bool
+	strategy RedactionStrategy
+}
+
+// RedactKey - redact the value of every field with a matching key.  The pattern is an exact key or a glob
+// (e.g. "password" or "*token*"), and it's not case sensitive.  Keys in nested objects (like headers) are matched too
+func RedactKey(pattern string, s RedactionStrategy) RedactionRule {
+	return RedactionRule{key: strings.ToLower(pattern), strategy: s}
+}
+
+// RedactValues - redact every match of the regexp in the message and string values.  When the regexp has a
+// group, only the first group is redacted
+func RedactValues(re *regexp.Regexp, s RedactionStrategy) RedactionRule {
+	return RedactionRule{value: re, strategy: s}
+}
+
+var (
+	emailRegexp       = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	creditCardRegexp  = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	jwtRegexp         = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+	bearerTokenRegexp = regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)
+)
+
+// RedactEmails - redact email addresses
+func RedactEmails(s RedactionStrategy) RedactionRule {
+	return RedactValues(emailRegexp, s)
+}
+
+// RedactCreditCards - redact credit card numbers (with or without spaces or dashes), which pass the Luhn check
+func RedactCreditCards(s RedactionStrategy) RedactionRule {
+	r := RedactValues(creditCardRegexp, s)
+	r.valid = luhn
+	return r
+}
+
+// RedactJWTs - redact JSON Web Tokens
+func RedactJWTs(s RedactionStrategy) RedactionRule {
+	return RedactValues(jwtRegexp, s)
+}
+
+// RedactBearerTokens - redact the token in "Bearer <token>"
+func RedactBearerTokens(s RedactionStrategy) RedactionRule {
+	return RedactValues(bearerTokenRegexp, s)
+}
+
+// Redactor - applies RedactionRule(s) to log entries and headers before they're output.  It's safe for concurrent use
+type Redactor struct {
+	keyRules   []RedactionRule
+	valueRules []RedactionRule
+}
+
+// NewRedactor - create a Redactor with the rules.  Key rules are applied before value rules
+// example:
+//
+//	r := ginlogrus.NewRedactor(
+//		ginlogrus.RedactKey("password", ginlogrus.RedactDrop),
+//		ginlogrus.RedactKey("*token*", ginlogrus.RedactMask),
+//		ginlogrus.RedactEmails(ginlogrus.RedactHash("my-salt")),
+//		ginlogrus.RedactCreditCards(ginlogrus.RedactMask))
+func NewRedactor(rules ...RedactionRule) *Redactor {
+	r := &Redactor{}
+	for _, rule := range rules {
+		if rule.value != nil {
+			r.valueRules = append(r.valueRules, rule)
+			continue
+		}
+		r.keyRules = append(r.keyRules, rule)
+	}
+	return r
+}
+
+// Fields - return a redacted copy of the fields
+func (r *Redactor) Fields(fields logrus.Fields) logrus.Fields {
+	redacted := make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		if v, keep := r.Value(k, v); keep {
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// Message - return the message with every value rule match redacted
+func (r *Redactor) Message(msg string) string {
+	s, _ := r.redactString(msg)
+	return s
+}
+
+// Entry - return a redacted copy of the LogEntry
+func (r *Redactor) Entry(e LogEntry) LogEntry {
+	e.Message = r.Message(e.Message)
+	e.Fields = r.Fields(e.Fields)
+	return e
+}
+
+// Value - redact the value for the key, which can be a nested object (e.g. map[string]interface{} or map[string]string).
+// It returns false when the value is dropped
+func (r *Redactor) Value(key string, v interface{}) (interface{}, bool) {
+	for _, rule := range r.keyRules {
+		if rule.matchesKey(key) {
+			return rule.strategy(fmt.Sprintf("%v", v))
+		}
+	}
+	switch v := v.(type) {
+	case string:
+		return r.redactString(v)
+	case logrus.Fields:
+		return r.Fields(v), true
+	case map[string]interface{}:
+		return map[string]interface{}(r.Fields(v)), true
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for k, s := range v {
+			if s, keep := r.Value(k, s); keep {
+				redacted[k] = s.(string)
+			}
+		}
+		return redacted, true
+	case []interface{}:
+		redacted := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			if e, keep := r.Value(key, e); keep {
+				redacted = append(redacted, e)
+			}
+		}
+		return redacted, true
+	case []string:
+		redacted := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, keep := r.redactString(s); keep {
+				redacted = append(redacted, s)
+			}
+		}
+		return redacted, true
+	}
+	// anything else (errors, fmt.Stringers, structs, pointers, numbers...) is redacted the way it's output
+	out := fmt.Sprint(v)
+	s, keep := r.redactString(out)
+	if s == out {
+		// leave the value alone, unless it's changed
+		return v, keep
+	}
+	return s, keep
+}
+
+// redactString - redact every value rule match in s.  It returns false when a match uses RedactDrop (and the
+// string without the matches)
+func (r *Redactor) redactString(s string) (string, bool) {
+	keep := true
+	for _, rule := range r.valueRules {
+		var out strings.Builder
+		last := 0
+		for _, m := range rule.value.FindAllStringSubmatchIndex(s, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if rule.valid != nil && !rule.valid(s[start:end]) {
+				continue
+			}
+			replacement, ok := rule.strategy(s[start:end])
+			keep = keep && ok
+			out.WriteString(s[last:start])
+			out.WriteString(replacement)
+			last = end
+		}
+		if last != 0 {
+			out.WriteString(s[last:])
+			s = out.String()
+		}
+	}
+	return s, keep
+}
+
+// matchesKey - does the key match the rule's exact key or glob
+func (rule RedactionRule) matchesKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == rule.key {
+		return true
+	}
+	matched, err := path.Match(rule.key, key)
+	return err == nil && matched
+}
+
+// Hook - a logrus.Hook which redacts every entry, for loggers that aren't managed by the middleware
+// example:
+//
+//	logrus.AddHook(redactor.Hook())
+func (r *Redactor) Hook() logrus.Hook {
+	return &redactionHook{r: r}
+}
+
+// redactionHook - a logrus.Hook that redacts the entry before it's formatted
+type redactionHook struct {
+	r *Redactor
+}
+
+// Levels - the hook fires for all levels
+func (h *redactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - redact the entry.  The fields are replaced (not modified), since logrus shares them between entries
+func (h *redactionHook) Fire(e *logrus.Entry) error {
+	e.Message = h.r.Message(e.Message)
+	e.Data = h.r.Fields(e.Data)
+	return nil
+}
+
+// redactorField - the field which marks the entries that are redacted by the markedRedactionHook.  It's removed before
+// the entry is formatted
+const redactorField = "ginlogrus-redactor"
+
+// markedRedactionHooks - the loggers which already have the markedRedactionHook
+var markedRedactionHooks sync.Map
+
+// markedRedactionHook - a logrus.Hook which only redacts the entries marked with a *Redactor in the redactorField, so
+// the entries of a request are redacted by the logger itself (and the logger's level, hooks and settings still apply)
+type markedRedactionHook struct{}
+
+// Levels - the hook fires for all levels
+func (markedRedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire - remove the marker and redact the entry.  The fields are replaced (not modified), since logrus shares them
+// between entries
+func (markedRedactionHook) Fire(e *logrus.Entry) error {
+	r, ok := e.Data[redactorField].(*Redactor)
+	if !ok {
+		return nil
+	}
+	fields := make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		if k != redactorField {
+			fields[k] = v
+		}
+	}
+	e.Message = r.Message(e.Message)
+	e.Data = r.Fields(fields)
+	return nil
+}
+
+// redactingEntry - when the middleware has a Redactor, return e marked so every entry from it is redacted (used when
+// not aggregate logging).  Otherwise it's just e
+func redactingEntry(c *gin.Context, e *logrus.Entry) *logrus.Entry {
+	r, found := c.Get(redactorKey)
+	if !found {
+		return e
+	}
+	return withRedaction(r.(*Redactor), e)
+}
+
+// withRedaction - return e marked so every entry from it is redacted with r, or just e when r is nil.  The entry's
+// logger gets the markedRedactionHook the first time
+func withRedaction(r *Redactor, e *logrus.Entry) *logrus.Entry {
+	if r == nil {
+		return e
+	}
+	if _, hooked := markedRedactionHooks.LoadOrStore(e.Logger, true); !hooked {
+		e.Logger.AddHook(markedRedactionHook{})
+	}
+	return e.WithField(redactorField, r)
+}
+
+// luhn - does the number (ignoring spaces and dashes) pass the Luhn check
+func luhn(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		ch := number[i]
+		if ch == ' ' || ch == '-' {
+			continue
+		}
+		d := int(ch - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
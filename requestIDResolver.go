@@ -0,0 +1,119 @@
+package ginlogrus
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// requestIDResolversKey - where the middleware stores its chain of RequestIDResolver(s) in the gin.Context
+const requestIDResolversKey = "request-id-resolvers"
+
+// RequestIDResolver - finds the request id for a request.  Resolvers are used in an ordered chain, and the first
+// one to find an id wins
+type RequestIDResolver interface {
+	Resolve(c *gin.Context) (string, bool)
+}
+
+// RequestIDResolverFunc - an adapter to allow the use of ordinary functions as a RequestIDResolver
+type RequestIDResolverFunc func(c *gin.Context) (string, bool)
+
+// Resolve - calls f(c)
+func (f RequestIDResolverFunc) Resolve(c *gin.Context) (string, bool) {
+	return f(c)
+}
+
+// HeaderResolver - find the request id in a request header (e.g. "uber-trace-id" or "X-Request-ID")
+func HeaderResolver(header string) RequestIDResolver {
+	return RequestIDResolverFunc(func(c *gin.Context) (string, bool) {
+		if c.Request == nil {
+			return "", false
+		}
+		id := c.Request.Header.Get(header)
+		return id, len(id) != 0
+	})
+}
+
+// ContextKeyResolver - find the request id in the gin.Context.  The value can be a string, an opentracing.Span
+// or a fmt.Stringer
+func ContextKeyResolver(key string) RequestIDResolver {
+	return RequestIDResolverFunc(func(c *gin.Context) (string, bool) {
+		v, found := c.Get(key)
+		if !found {
+			return "", false
+		}
+		var id string
+		switch v := v.(type) {
+		case string:
+			id = v
+		case opentracing.Span:
+			id = fmt.Sprintf("%v", v)
+		case fmt.Stringer:
+			id = v.String()
+		}
+		return id, len(id) != 0
+	})
+}
+
+// OpenTracingSpanResolver - use the span set by github.com/Bose/go-gin-opentracing in "tracing-context"
+func OpenTracingSpanResolver() RequestIDResolver {
+	return ContextKeyResolver("tracing-context")
+}
+
+// OTelSpanResolver - use the trace id of the active OpenTelemetry span in the request's context.Context
+func OTelSpanResolver() RequestIDResolver {
+	return RequestIDResolverFunc(func(c *gin.Context) (string, bool) {
+		sc, ok := CxtOTelSpanContext(c)
+		if !ok {
+			return "", false
+		}
+		return sc.TraceID().String(), true
+	})
+}
+
+// TraceParentResolver - use the trace-id from the W3C traceparent header
+func TraceParentResolver() RequestIDResolver {
+	return RequestIDResolverFunc(func(c *gin.Context) (string, bool) {
+		tc, ok := CxtTraceContext(c)
+		if !ok {
+			return "", false
+		}
+		return tc.TraceID, true
+	})
+}
+
+// GeneratorResolver - always generates a new id with the IDGenerator, so it's usually the last resolver in the chain
+func GeneratorResolver(g IDGenerator) RequestIDResolver {
+	return RequestIDResolverFunc(func(c *gin.Context) (string, bool) {
+		return g.NewID(), true
+	})
+}
+
+// UUIDResolver - always generates a new uuid, so it's usually the last resolver in the chain
+func UUIDResolver() RequestIDResolver {
+	return GeneratorResolver(UUIDGenerator)
+}
+
+// defaultRequestIDResolvers - the chain used when WithRequestIDResolvers() isn't used: the go-gin-opentracing span,
+// the gin.Context key, the trace id header, the W3C traceparent and then a generated id
+func defaultRequestIDResolvers(contextTraceIDField, traceIDHeader string, g IDGenerator) []RequestIDResolver {
+	resolvers := []RequestIDResolver{OpenTracingSpanResolver()}
+	if len(contextTraceIDField) != 0 {
+		resolvers = append(resolvers, ContextKeyResolver(contextTraceIDField))
+	}
+	if len(traceIDHeader) != 0 {
+		resolvers = append(resolvers, HeaderResolver(traceIDHeader))
+	}
+	return append(resolvers, TraceParentResolver(), GeneratorResolver(g))
+}
+
+// resolveRequestID - find the request id with the first resolver in the chain that can
+func resolveRequestID(c *gin.Context, resolvers []RequestIDResolver) (string, bool) {
+	for _, r := range resolvers {
+		if id, ok := r.Resolve(c); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,158 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+)
+
+type testStringer string
+
+func (s testStringer) String() string { return string(s) }
+
+func TestRequestIDResolvers(t *testing.T) {
+	tracer := opentracing.NoopTracer{}
+	tests := []struct {
+		name     string
+		resolver RequestIDResolver
+		setup    func(c *gin.Context)
+		want     string
+		wantOK   bool
+	}{
+		{
+			name:     "header",
+			resolver: HeaderResolver("X-Request-ID"),
+			setup:    func(c *gin.Context) { c.Request.Header.Set("X-Request-ID", "from-header") },
+			want:     "from-header",
+			wantOK:   true,
+		},
+		{
+			name:     "header-missing",
+			resolver: HeaderResolver("X-Request-ID"),
+			setup:    func(c *gin.Context) {},
+		},
+		{
+			name:     "context-string",
+			resolver: ContextKeyResolver("my-id"),
+			setup:    func(c *gin.Context) { c.Set("my-id", "from-context") },
+			want:     "from-context",
+			wantOK:   true,
+		},
+		{
+			name:     "context-stringer",
+			resolver: ContextKeyResolver("my-id"),
+			setup:    func(c *gin.Context) { c.Set("my-id", testStringer("from-stringer")) },
+			want:     "from-stringer",
+			wantOK:   true,
+		},
+		{
+			name:     "context-unknown-type",
+			resolver: ContextKeyResolver("my-id"),
+			setup:    func(c *gin.Context) { c.Set("my-id", 42) },
+		},
+		{
+			name:     "opentracing-span",
+			resolver: OpenTracingSpanResolver(),
+			setup:    func(c *gin.Context) { c.Set("tracing-context", tracer.StartSpan("test")) },
+			want:     "{}",
+			wantOK:   true,
+		},
+		{
+			name:     "traceparent",
+			resolver: TraceParentResolver(),
+			setup: func(c *gin.Context) {
+				c.Request.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			},
+			want:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantOK: true,
+		},
+		{
+			name:     "no-otel-span",
+			resolver: OTelSpanResolver(),
+			setup:    func(c *gin.Context) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := getTestContext("boo", "bar", false)
+			tt.setup(c)
+			got, ok := tt.resolver.Resolve(c)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Resolve() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	c := getTestContext("boo", "bar", false)
+	if id, ok := UUIDResolver().Resolve(c); !ok || len(id) != 36 {
+		t.Errorf("UUIDResolver().Resolve() = %v, %v", id, ok)
+	}
+}
+
+func TestWithRequestIDResolvers(t *testing.T) {
+	is := is.New(t)
+	var handlerRequestID string
+	getHandler := func(c *gin.Context) {
+		handlerRequestID = CxtRequestID(c)
+		GetCtxLogger(c).Info("test-entry-1")
+		c.JSON(200, "Hello world!")
+	}
+	gin.SetMode(gin.DebugMode)
+	gin.DisableConsoleColor()
+
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(New(
+		WithAggregateLogging(true),
+		WithWriter(l),
+		WithRequestIDResolvers(
+			HeaderResolver("X-Request-ID"),
+			RequestIDResolverFunc(func(c *gin.Context) (string, bool) { return "fallback-id", true }))))
+	r.GET("/", getHandler)
+
+	w := performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	t.Log("this is the buffer: ", l)
+	is.Equal(handlerRequestID, "fallback-id")
+	is.True(strings.Contains(l.String(), `"requestID":"fallback-id"`))
+
+	// the middleware and CxtRequestID() agree when the id comes from a context key set by the handler
+	l.Reset()
+	r = gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(l), WithContextTraceIDField("my-id")))
+	r.GET("/", func(c *gin.Context) {
+		c.Set("my-id", testStringer("from-handler"))
+		handlerRequestID = CxtRequestID(c)
+		c.JSON(200, "Hello world!")
+	})
+	w = performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	is.Equal(handlerRequestID, "from-handler")
+	is.True(strings.Contains(l.String(), `"requestID":"from-handler"`))
+}
+
+func TestWithTracing_RequestIDSetLater(t *testing.T) {
+	is := is.New(t)
+	gin.SetMode(gin.DebugMode)
+	l := bytes.NewBufferString("")
+	r := gin.New()
+	r.Use(WithTracing(logrus.StandardLogger(), false, time.RFC3339, true, "requestID", []byte("uber-trace-id"), []byte("X-ID"),
+		WithAggregateLogging(true), WithWriter(l)))
+	// a middleware after the logger (like go-gin-opentracing) sets the id
+	r.Use(func(c *gin.Context) {
+		c.Set("X-ID", "late-id")
+	})
+	r.GET("/", func(c *gin.Context) {
+		GetCtxLogger(c).Info("test-entry-1")
+		c.JSON(200, "Hello world!")
+	})
+	w := performRequest("GET", "/", r)
+	is.Equal(200, w.Code)
+	is.True(strings.Contains(l.String(), `"requestID":"late-id"`))
+}
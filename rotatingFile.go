@@ -0,0 +1,342 @@
+package ginlogrus
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat - the time in the names of rotated files, which sorts from oldest to newest
+const rotatedTimeFormat = "20060102T150405.000"
+
+// ErrRotatingFileClosed - returned by a RotatingFile after Close() has been called
+var ErrRotatingFileClosed = errors.New("rotating file is closed")
+
+// RotatingFile - an io.Writer for a file which is rotated by size and/or time (see RotatingFileOption), so it can be
+// passed to WithWriter() when there isn't a log agent.  Rotated files are renamed with the time they were rotated (e.g.
+// app-20200101T000000.000.log for app.log).  Every Write() goes to one file, and it's safe for concurrent writers
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	opts     rotatingFileOptions
+	now      func() time.Time
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	rotatedMU sync.Mutex // serializes compressing and removing rotated files
+	rotated   sync.WaitGroup
+	signals   chan os.Signal
+	done      chan struct{}
+}
+
+// NewRotatingFile - create a RotatingFile which appends to the file at path (and its directory when it doesn't exist)
+func NewRotatingFile(path string, opt ...RotatingFileOption) (*RotatingFile, error) {
+	opts := defaultRotatingFileOptions()
+	for _, o := range opt {
+		o(&opts)
+	}
+	f := &RotatingFile{
+		path: path,
+		opts: opts,
+		now:  time.Now,
+		done: make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	if opts.reopenOnSIGHUP && len(reopenSignals) != 0 {
+		f.signals = make(chan os.Signal, 1)
+		signal.Notify(f.signals, reopenSignals...)
+		go f.reopenOnSignal()
+	}
+	if opts.rotateEvery > 0 {
+		go f.rotateOnTime()
+	}
+	return f, nil
+}
+
+// Write - write p to the file, rotating it first when it's too big or too old
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, ErrRotatingFileClosed
+	}
+	if f.shouldRotate(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate - rotate the file now
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrRotatingFileClosed
+	}
+	return f.rotate()
+}
+
+// Reopen - close and reopen the file, which is needed after it's been moved by something else (like logrotate)
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrRotatingFileClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close - close the file, and wait until the rotated files are compressed and removed
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	if f.signals != nil {
+		signal.Stop(f.signals)
+	}
+	close(f.done)
+	err := f.file.Close()
+	f.mu.Unlock()
+	f.rotated.Wait()
+	return err
+}
+
+// reopenOnSignal - reopen the file for every signal, until it's closed
+func (f *RotatingFile) reopenOnSignal() {
+	for {
+		select {
+		case <-f.signals:
+			if err := f.Reopen(); err != nil && err != ErrRotatingFileClosed {
+				fmt.Fprintln(os.Stderr, "Error reopening log file:", err)
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// rotateOnTime - rotate the file at the start of every rotateEvery period, so a file that isn't written to is rotated
+// too (until it's closed)
+func (f *RotatingFile) rotateOnTime() {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(f.opts.rotateEvery).Add(f.opts.rotateEvery).Sub(now))
+		select {
+		case <-timer.C:
+			f.mu.Lock()
+			if !f.closed && f.shouldRotate(0) {
+				if err := f.rotate(); err != nil {
+					fmt.Fprintln(os.Stderr, "Error rotating log file:", err)
+				}
+			}
+			f.mu.Unlock()
+		case <-f.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// open - open the file for appending, the caller must hold mu
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	if f.size != 0 {
+		// the file was written before (e.g. before a restart), so it's as old as its last write
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// shouldRotate - is the file too big for the write or was it opened before the current rotateEvery period started, the
+// caller must hold mu.  An empty file is never rotated
+func (f *RotatingFile) shouldRotate(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	return (f.opts.maxFileSize > 0 && f.size+int64(n) > f.opts.maxFileSize) ||
+		(f.opts.rotateEvery > 0 && f.now().Truncate(f.opts.rotateEvery).After(f.openedAt))
+}
+
+// rotate - rename the file with the time, and open a new one.  The rotated file is compressed and the old ones are
+// removed in the background.  The caller must hold mu
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	rotated := f.rotatedName()
+	if err := os.Rename(f.path, rotated); err != nil {
+		// keep writing to the same file
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.rotated.Add(1)
+	go func() {
+		defer f.rotated.Done()
+		f.rotatedMU.Lock()
+		defer f.rotatedMU.Unlock()
+		if f.opts.compress {
+			// a newer rotation may have already removed it
+			if err := compressFile(rotated); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintln(os.Stderr, "Error compressing rotated log file:", err)
+			}
+		}
+		if err := f.removeOldBackups(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error removing old log files:", err)
+		}
+	}()
+	return nil
+}
+
+// rotatedName - the name for the file when it's rotated now, which doesn't exist yet
+func (f *RotatingFile) rotatedName() string {
+	prefix, ext := f.backupPrefix()
+	name := prefix + f.now().Format(rotatedTimeFormat)
+	rotated := name + ext
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s-%d%s", name, i, ext)
+	}
+	return rotated
+}
+
+// backupPrefix - the rotated files are <prefix><time><ext>, with an optional ".gz"
+func (f *RotatingFile) backupPrefix() (prefix, ext string) {
+	ext = filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-", ext
+}
+
+// removeOldBackups - remove the oldest rotated files, so there are at most maxBackups
+func (f *RotatingFile) removeOldBackups() error {
+	if f.opts.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(backups) > f.opts.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			errs = append(errs, err)
+		}
+		backups = backups[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// backups - the rotated files (compressed or not), from oldest to newest
+func (f *RotatingFile) backups() ([]string, error) {
+	prefix, ext := f.backupPrefix()
+	dir, base := filepath.Split(prefix)
+	entries, err := os.ReadDir(filepath.Dir(prefix))
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		name    string
+		rotated string
+	}
+	var found []backup
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Name(), base)
+		if !ok || e.IsDir() {
+			continue
+		}
+		rest = strings.TrimSuffix(strings.TrimSuffix(rest, ".gz"), ext)
+		if len(rest) < len(rotatedTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(rotatedTimeFormat, rest[:len(rotatedTimeFormat)]); err != nil {
+			continue
+		}
+		found = append(found, backup{name: dir + e.Name(), rotated: rest})
+	}
+	// files rotated in the same millisecond have a -N suffix, so they sort after the one without it
+	sort.Slice(found, func(i, j int) bool {
+		ti, tj := found[i].rotated[:len(rotatedTimeFormat)], found[j].rotated[:len(rotatedTimeFormat)]
+		if ti != tj {
+			return ti < tj
+		}
+		return len(found[i].rotated) < len(found[j].rotated) || (len(found[i].rotated) == len(found[j].rotated) && found[i].rotated < found[j].rotated)
+	})
+	backups := make([]string, 0, len(found))
+	for _, b := range found {
+		backups = append(backups, b.name)
+	}
+	return backups, nil
+}
+
+// compressFile - gzip the file to name.gz, and remove it
+func compressFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(name)
+}
+
+// fileExists - does the file exist
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
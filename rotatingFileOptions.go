@@ -0,0 +1,58 @@
+package ginlogrus
+
+import "time"
+
+// RotatingFileOption - define options for NewRotatingFile()
+type RotatingFileOption func(*rotatingFileOptions)
+type rotatingFileOptions struct {
+	maxFileSize    int64
+	rotateEvery    time.Duration
+	maxBackups     int
+	compress       bool
+	reopenOnSIGHUP bool
+}
+
+func defaultRotatingFileOptions() rotatingFileOptions {
+	return rotatingFileOptions{}
+}
+
+// WithMaxFileSize - define an Option func for rotating the file before a write would make it bigger than maxBytes, the
+// default is 0 which doesn't rotate by size
+func WithMaxFileSize(maxBytes int64) RotatingFileOption {
+	return func(o *rotatingFileOptions) {
+		o.maxFileSize = maxBytes
+	}
+}
+
+// WithRotateEvery - define an Option func for rotating the file at the start of every period of d (e.g. 24*time.Hour
+// rotates at midnight UTC), the default is 0 which doesn't rotate by time
+func WithRotateEvery(d time.Duration) RotatingFileOption {
+	return func(o *rotatingFileOptions) {
+		o.rotateEvery = d
+	}
+}
+
+// WithMaxBackups - define an Option func for the number of rotated files that are kept (the oldest are removed), the
+// default is 0 which keeps all of them
+func WithMaxBackups(n int) RotatingFileOption {
+	return func(o *rotatingFileOptions) {
+		o.maxBackups = n
+	}
+}
+
+// WithCompress - define an Option func for gzipping rotated files (in the background), the default is false
+func WithCompress(a bool) RotatingFileOption {
+	return func(o *rotatingFileOptions) {
+		o.compress = a
+	}
+}
+
+// WithReopenOnSIGHUP - define an Option func for reopening the file when the process gets a SIGHUP (e.g. from logrotate
+// after it moved the file), the default is false.  It's process wide: while the file is open, a SIGHUP no longer stops the
+// process (the Go default), and every other signal.Notify() for SIGHUP still gets it too.  It's ignored on platforms without
+// SIGHUP
+func WithReopenOnSIGHUP(a bool) RotatingFileOption {
+	return func(o *rotatingFileOptions) {
+		o.reopenOnSIGHUP = a
+	}
+}
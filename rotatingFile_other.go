@@ -0,0 +1,8 @@
+//go:build !unix
+
+package ginlogrus
+
+import "os"
+
+// reopenSignals - the signals which reopen a RotatingFile, and there's no SIGHUP on this platform
+var reopenSignals []os.Signal
@@ -0,0 +1,263 @@
+package ginlogrus
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+// readLogFiles - the contents of the files in dir by name, unzipping the .gz files
+func readLogFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(e.Name(), ".gz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[e.Name()] = string(b)
+	}
+	return files
+}
+
+// sortedKeys - the names of the files
+func sortedKeys(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []RotatingFileOption
+		writes    int
+		every     time.Duration // how much time passes between writes
+		wantFiles []string
+		wantLogs  []string // the contents of wantFiles
+	}{
+		{
+			name:      "no-rotation",
+			writes:    3,
+			wantFiles: []string{"app.log"},
+			wantLogs:  []string{"line 0\nline 1\nline 2\n"},
+		},
+		{
+			name:      "size",
+			opts:      []RotatingFileOption{WithMaxFileSize(14)},
+			writes:    5,
+			every:     time.Second,
+			wantFiles: []string{"app-20200101T000002.000.log", "app-20200101T000004.000.log", "app.log"},
+			wantLogs:  []string{"line 0\nline 1\n", "line 2\nline 3\n", "line 4\n"},
+		},
+		{
+			name:      "time",
+			opts:      []RotatingFileOption{WithRotateEvery(time.Minute)},
+			writes:    4,
+			every:     30 * time.Second,
+			wantFiles: []string{"app-20200101T000100.000.log", "app.log"},
+			wantLogs:  []string{"line 0\nline 1\n", "line 2\nline 3\n"},
+		},
+		{
+			name:      "max-backups",
+			opts:      []RotatingFileOption{WithMaxFileSize(7), WithMaxBackups(2)},
+			writes:    5,
+			every:     time.Second,
+			wantFiles: []string{"app-20200101T000003.000.log", "app-20200101T000004.000.log", "app.log"},
+			wantLogs:  []string{"line 2\n", "line 3\n", "line 4\n"},
+		},
+		{
+			name:      "same-millisecond",
+			opts:      []RotatingFileOption{WithMaxFileSize(7), WithMaxBackups(2)},
+			writes:    4,
+			wantFiles: []string{"app-20200101T000000.000-1.log", "app-20200101T000000.000-2.log", "app.log"},
+			wantLogs:  []string{"line 1\n", "line 2\n", "line 3\n"},
+		},
+		{
+			name:      "compress",
+			opts:      []RotatingFileOption{WithMaxFileSize(7), WithCompress(true), WithMaxBackups(1)},
+			writes:    3,
+			every:     time.Second,
+			wantFiles: []string{"app-20200101T000002.000.log.gz", "app.log"},
+			wantLogs:  []string{"line 1\n", "line 2\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			f, err := NewRotatingFile(filepath.Join(dir, "app.log"), append(tt.opts, WithReopenOnSIGHUP(false))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.now = func() time.Time { return now }
+			f.openedAt = now
+			for i := 0; i < tt.writes; i++ {
+				if i != 0 {
+					now = now.Add(tt.every)
+				}
+				if _, err := fmt.Fprintf(f, "line %d\n", i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			files := readLogFiles(t, dir)
+			if got := sortedKeys(files); strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Fatalf("files = %v, want %v", got, tt.wantFiles)
+			}
+			for i, name := range tt.wantFiles {
+				if files[name] != tt.wantLogs[i] {
+					t.Errorf("%s = %q, want %q", name, files[name], tt.wantLogs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewRotatingFile(path, WithReopenOnSIGHUP(false))
+	is.NoErr(err)
+	_, _ = f.Write([]byte("before\n"))
+
+	// like logrotate, which moves the file and then tells the process to reopen it
+	is.NoErr(os.Rename(path, filepath.Join(dir, "app.log.1")))
+	is.NoErr(f.Reopen())
+	_, _ = f.Write([]byte("after\n"))
+	is.NoErr(f.Close())
+
+	files := readLogFiles(t, dir)
+	is.Equal(files["app.log.1"], "before\n")
+	is.Equal(files["app.log"], "after\n")
+
+	_, err = f.Write([]byte("too late\n"))
+	is.Equal(err, ErrRotatingFileClosed)
+	is.NoErr(f.Close()) // closing twice is fine
+}
+
+func TestRotatingFileAfterRestart(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// the file was last written two days ago, before the service restarted
+	is.NoErr(os.WriteFile(path, []byte("before the restart\n"), 0644))
+	twoDaysAgo := time.Now().Add(-48 * time.Hour)
+	is.NoErr(os.Chtimes(path, twoDaysAgo, twoDaysAgo))
+
+	f, err := NewRotatingFile(path, WithRotateEvery(24*time.Hour), WithReopenOnSIGHUP(false))
+	is.NoErr(err)
+	_, err = f.Write([]byte("after the restart\n"))
+	is.NoErr(err)
+	is.NoErr(f.Close())
+
+	files := readLogFiles(t, dir)
+	is.Equal(len(files), 2)
+	is.Equal(files["app.log"], "after the restart\n")
+}
+
+func TestRotatingFileIdle(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	f, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithRotateEvery(50*time.Millisecond), WithReopenOnSIGHUP(false))
+	is.NoErr(err)
+	_, err = f.Write([]byte("the only line\n"))
+	is.NoErr(err)
+
+	// nothing else is written, and the file is still rotated once the period is over
+	deadline := time.Now().Add(2 * time.Second)
+	for len(readLogFiles(t, dir)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	is.NoErr(f.Close())
+	files := readLogFiles(t, dir)
+	is.Equal(len(files), 2)
+	is.Equal(files["app.log"], "")
+}
+
+func TestRotatingFileConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewRotatingFile(filepath.Join(dir, "app.log"), WithMaxFileSize(1000), WithReopenOnSIGHUP(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				fmt.Fprintf(f, "{\"writer\":%d,\"line\":%d}\n", w, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	for name, content := range readLogFiles(t, dir) {
+		if len(content) > 1000 {
+			t.Errorf("%s has %d bytes, want at most 1000", name, len(content))
+		}
+		for _, l := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if !strings.HasPrefix(l, "{\"writer\":") || !strings.HasSuffix(l, "}") {
+				t.Errorf("%s has a broken line %q", name, l)
+			}
+			lines++
+		}
+	}
+	if lines != 1000 {
+		t.Errorf("lines = %d, want 1000", lines)
+	}
+}
+
+func TestRotatingFileWithMiddleware(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	f, err := NewRotatingFile(filepath.Join(dir, "logs", "app.log"), WithReopenOnSIGHUP(false))
+	is.NoErr(err)
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(f)))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(200, "Hello world!")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	is.NoErr(f.Close())
+	files := readLogFiles(t, filepath.Join(dir, "logs"))
+	is.True(strings.Contains(files["app.log"], `"request-summary-info"`))
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package ginlogrus
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals - the signals which reopen a RotatingFile
+var reopenSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,45 @@
+//go:build unix
+
+package ginlogrus
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewRotatingFile(path, WithReopenOnSIGHUP(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !fileExists(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("the file wasn't reopened after a SIGHUP")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRotatingFileSIGHUPIsOptIn(t *testing.T) {
+	f, err := NewRotatingFile(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.signals != nil {
+		t.Error("NewRotatingFile() listens for SIGHUP without WithReopenOnSIGHUP(true)")
+	}
+}
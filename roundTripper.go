@@ -0,0 +1,177 @@
+package ginlogrus
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// roundTripper - an http.RoundTripper which logs every outbound request into the request's aggregate
+type roundTripper struct {
+	logger  *logrus.Entry
+	headers http.Header // nil when the request is found via the outbound request's context.Context
+	next    http.RoundTripper
+	opts    roundTripperOptions
+}
+
+// NewRoundTripper - wrap next (http.DefaultTransport when nil) so every outbound request made for the gin.Context is
+// logged (method, host, path, status, latency, retries and error) with GetCtxLogger(c), which means it's added to the
+// current aggregate when aggregate logging.  The request id and trace headers are also propagated via InjectRequestHeaders()
+// (as they are when the RoundTripper is created, so create it once the request id is set)
+// example:
+//
+//	client := &http.Client{Transport: ginlogrus.NewRoundTripper(c, nil)}
+//	resp, err := client.Get("http://downstream/api")
+func NewRoundTripper(c *gin.Context, next http.RoundTripper, opt ...RoundTripperOption) http.RoundTripper {
+	rt := newRoundTripper(next, opt...)
+	// get everything from the gin.Context now, since the client may be used from go routines or after the request is
+	// over (when gin has recycled the gin.Context)
+	rt.logger = GetCtxLogger(c)
+	rt.headers = http.Header{}
+	InjectRequestHeaders(c, &http.Request{Header: rt.headers})
+	return rt
+}
+
+// NewContextRoundTripper - just like NewRoundTripper(), except the request is found via the outbound request's
+// context.Context, which must come from the incoming c.Request.Context() (the middleware attaches the request's logger,
+// request id and trace headers to it, so it's safe to use from go routines).
+// It can be shared by all requests, and outbound requests without a request context are just passed to next
+// example:
+//
+//	client := &http.Client{Transport: ginlogrus.NewContextRoundTripper(nil)}
+//	req, _ := http.NewRequestWithContext(ctx, "GET", "http://downstream/api", nil)
+//	resp, err := client.Do(req)
+func NewContextRoundTripper(next http.RoundTripper, opt ...RoundTripperOption) http.RoundTripper {
+	return newRoundTripper(next, opt...)
+}
+
+func newRoundTripper(next http.RoundTripper, opt ...RoundTripperOption) *roundTripper {
+	opts := defaultRoundTripperOptions()
+	for _, o := range opt {
+		o(&opts)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{next: next, opts: opts}
+}
+
+// RoundTrip - implement http.RoundTripper
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	logger := rt.logger
+	// a RoundTripper mustn't modify the request, so the headers are added to a clone
+	var out *http.Request
+	if rt.headers != nil {
+		out = req.Clone(req.Context())
+		for k, v := range rt.headers {
+			out.Header[k] = v
+		}
+	} else {
+		rc, found := requestContextFrom(req.Context())
+		if !found {
+			resp, _, err := rt.roundTrip(req)
+			return resp, err
+		}
+		logger = FromContext(req.Context())
+		out = req.Clone(req.Context())
+		for k, v := range rc.getHeaders() {
+			out.Header[k] = v
+		}
+		if trace.SpanContextFromContext(req.Context()).IsValid() {
+			// the outbound request may be in a child span of the request's span
+			propagation.TraceContext{}.Inject(req.Context(), propagation.HeaderCarrier(out.Header))
+		}
+	}
+
+	start := time.Now()
+	resp, retries, err := rt.roundTrip(out)
+	latency := time.Since(start)
+
+	fields := logrus.Fields{
+		"outbound-method":     req.Method,
+		"outbound-host":       req.URL.Host,
+		"outbound-path":       req.URL.Path,
+		"outbound-latency-ms": float64(latency) / float64(time.Millisecond),
+		"outbound-retries":    retries,
+	}
+	switch {
+	case err != nil:
+		fields["outbound-error"] = err.Error()
+		logger.WithFields(fields).Error("outbound request")
+	case resp.StatusCode >= http.StatusInternalServerError:
+		fields["outbound-status"] = resp.StatusCode
+		logger.WithFields(fields).Warn("outbound request")
+	default:
+		fields["outbound-status"] = resp.StatusCode
+		logger.WithFields(fields).Info("outbound request")
+	}
+	return resp, err
+}
+
+// roundTrip - send the request with next, retrying it when the retry policy says so.  It returns the number of retries
+func (rt *roundTripper) roundTrip(req *http.Request) (*http.Response, int, error) {
+	backoff := rt.opts.retryBackoff
+	attempt := req
+	for retries := 0; ; retries++ {
+		resp, err := rt.next.RoundTrip(attempt)
+		if retries >= rt.opts.maxRetries || !rt.opts.retryPolicy(req, resp, err) {
+			return resp, retries, err
+		}
+		// the body has to be sent again, so give up if it can't be
+		next, ok := rewind(req)
+		if !ok {
+			return resp, retries, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, retries, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		attempt = next
+	}
+}
+
+// rewind - clone the request with a fresh body for another attempt
+func rewind(req *http.Request) (*http.Request, bool) {
+	r := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return r, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	r.Body = body
+	return r, true
+}
+
+// DefaultRetryPolicy - retry idempotent requests after an error (unless the request was canceled) or a 502, 503 or
+// 504 response
+func DefaultRetryPolicy(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
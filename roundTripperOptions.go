@@ -0,0 +1,40 @@
+package ginlogrus
+
+import (
+	"net/http"
+	"time"
+)
+
+// RoundTripperOption - define options for NewRoundTripper() and NewContextRoundTripper()
+type RoundTripperOption func(*roundTripperOptions)
+type roundTripperOptions struct {
+	maxRetries   int
+	retryBackoff time.Duration
+	retryPolicy  RetryPolicy
+}
+
+// RetryPolicy - decide if an outbound request should be retried, given the response or error from the last attempt
+type RetryPolicy func(req *http.Request, resp *http.Response, err error) bool
+
+func defaultRoundTripperOptions() roundTripperOptions {
+	return roundTripperOptions{
+		retryBackoff: 100 * time.Millisecond,
+		retryPolicy:  DefaultRetryPolicy,
+	}
+}
+
+// WithRetries - define an Option func for retrying outbound requests up to max times, waiting backoff (doubled after
+// every attempt) between them.  The default is no retries
+func WithRetries(max int, backoff time.Duration) RoundTripperOption {
+	return func(o *roundTripperOptions) {
+		o.maxRetries = max
+		o.retryBackoff = backoff
+	}
+}
+
+// WithRetryPolicy - define an Option func for deciding which outbound requests are retried, the default is DefaultRetryPolicy
+func WithRetryPolicy(p RetryPolicy) RoundTripperOption {
+	return func(o *roundTripperOptions) {
+		o.retryPolicy = p
+	}
+}
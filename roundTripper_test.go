@@ -0,0 +1,211 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+// outboundEntries - run a request through the middleware (with aggregate logging) and return the outbound request entries
+func outboundEntries(t *testing.T, handler gin.HandlerFunc, method string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(&out)))
+	r.Handle(method, "/", handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, "/", nil))
+
+	var aggregate struct {
+		Entries []map[string]interface{} `json:"entries"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &aggregate); err != nil {
+		t.Fatalf("unable to parse the aggregate %q: %v", out.String(), err)
+	}
+	var entries []map[string]interface{}
+	for _, e := range aggregate.Entries {
+		if e["msg"] == "outbound request" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func TestNewRoundTripper(t *testing.T) {
+	is := is.New(t)
+	var downstreamRequestID string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamRequestID = r.Header.Get(DefaultRequestIDHeader)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer downstream.Close()
+
+	var requestID string
+	entries := outboundEntries(t, func(c *gin.Context) {
+		requestID = CxtRequestID(c)
+		client := &http.Client{Transport: NewRoundTripper(c, nil)}
+		req, _ := http.NewRequest("GET", downstream.URL+"/api/things", nil)
+		resp, err := client.Do(req)
+		is.NoErr(err)
+		resp.Body.Close()
+		is.Equal(req.Header.Get(DefaultRequestIDHeader), "") // the outbound request wasn't modified
+		c.JSON(200, "Hello world!")
+	}, "GET")
+	is.Equal(downstreamRequestID, requestID)
+	is.Equal(len(entries), 1)
+	e := entries[0]
+	is.Equal(e["level"], "info")
+	is.Equal(e["outbound-method"], "GET")
+	is.Equal(e["outbound-host"], strings.TrimPrefix(downstream.URL, "http://"))
+	is.Equal(e["outbound-path"], "/api/things")
+	is.Equal(e["outbound-status"], float64(http.StatusTeapot))
+	is.Equal(e["outbound-retries"], float64(0))
+	_, ok := e["outbound-latency-ms"].(float64)
+	is.True(ok)
+}
+
+func TestNewRoundTripper_GoRoutines(t *testing.T) {
+	is := is.New(t)
+	var downstreamRequestID string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamRequestID = r.Header.Get(DefaultRequestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer downstream.Close()
+
+	var requestID string
+	outboundEntries(t, func(c *gin.Context) {
+		requestID = CxtRequestID(c)
+		client := &http.Client{Transport: NewRoundTripper(c, nil)}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the RoundTripper doesn't use the gin.Context, so this doesn't race with the handler
+			resp, err := client.Get(downstream.URL + "/api/things")
+			is.NoErr(err)
+			resp.Body.Close()
+		}()
+		for i := 0; i < 100; i++ {
+			c.Set(fmt.Sprintf("key-%d", i), i)
+		}
+		wg.Wait()
+		c.JSON(200, "Hello world!")
+	}, "GET")
+	is.Equal(downstreamRequestID, requestID)
+}
+
+func TestNewContextRoundTripper(t *testing.T) {
+	is := is.New(t)
+	var downstreamRequestID string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamRequestID = r.Header.Get(DefaultRequestIDHeader)
+	}))
+	defer downstream.Close()
+	client := &http.Client{Transport: NewContextRoundTripper(nil)}
+
+	// a service layer func that only gets a context.Context
+	var requestID string
+	entries := outboundEntries(t, func(c *gin.Context) {
+		requestID = CxtRequestID(c)
+		req, _ := http.NewRequest("GET", downstream.URL, nil)
+		resp, err := client.Do(req.WithContext(c.Request.Context()))
+		is.NoErr(err)
+		resp.Body.Close()
+		c.JSON(200, "Hello world!")
+	}, "GET")
+	is.Equal(downstreamRequestID, requestID)
+	is.Equal(len(entries), 1)
+	is.Equal(entries[0]["outbound-status"], float64(200))
+
+	// without a request context, it's just passed along
+	resp, err := client.Get(downstream.URL)
+	is.NoErr(err)
+	resp.Body.Close()
+	is.Equal(downstreamRequestID, "")
+}
+
+func TestRoundTripper_Retries(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		failures    int32
+		opts        []RoundTripperOption
+		wantRetries float64
+		wantStatus  float64
+		wantLevel   string
+	}{
+		{"no-retries", "GET", 1, nil, 0, 503, "warning"},
+		{"retried", "GET", 2, []RoundTripperOption{WithRetries(3, time.Millisecond)}, 2, 200, "info"},
+		{"out-of-retries", "GET", 5, []RoundTripperOption{WithRetries(2, time.Millisecond)}, 2, 503, "warning"},
+		{"not-idempotent", "POST", 1, []RoundTripperOption{WithRetries(3, time.Millisecond)}, 0, 503, "warning"},
+		{"custom-policy", "POST", 1, []RoundTripperOption{
+			WithRetries(3, time.Millisecond),
+			WithRetryPolicy(func(req *http.Request, resp *http.Response, err error) bool {
+				return err != nil || resp.StatusCode == http.StatusServiceUnavailable
+			})}, 1, 200, "info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var calls int32
+			downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer downstream.Close()
+
+			entries := outboundEntries(t, func(c *gin.Context) {
+				client := &http.Client{Transport: NewRoundTripper(c, nil, tt.opts...)}
+				req, _ := http.NewRequest(tt.method, downstream.URL, strings.NewReader("body"))
+				resp, err := client.Do(req)
+				is.NoErr(err)
+				resp.Body.Close()
+				c.JSON(200, "Hello world!")
+			}, tt.method)
+			is.Equal(len(entries), 1)
+			is.Equal(entries[0]["outbound-retries"], tt.wantRetries)
+			is.Equal(entries[0]["outbound-status"], tt.wantStatus)
+			is.Equal(entries[0]["level"], tt.wantLevel)
+		})
+	}
+}
+
+func TestRoundTripper_Error(t *testing.T) {
+	is := is.New(t)
+	failing := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	entries := outboundEntries(t, func(c *gin.Context) {
+		client := &http.Client{Transport: NewRoundTripper(c, failing, WithRetries(1, time.Millisecond))}
+		_, err := client.Get("http://downstream.example.com/api")
+		is.True(err != nil)
+		c.JSON(200, "Hello world!")
+	}, "GET")
+	is.Equal(len(entries), 1)
+	is.Equal(entries[0]["level"], "error")
+	is.Equal(entries[0]["outbound-error"], "connection refused")
+	is.Equal(entries[0]["outbound-retries"], float64(1))
+	_, found := entries[0]["outbound-status"]
+	is.True(!found)
+}
+
+// roundTripperFunc - an adapter to allow the use of ordinary functions as an http.RoundTripper in tests
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,152 @@
+package ginlogrus
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// loggedPathKey - where the middleware stores the request path with the redacted route params, when it's not the same as
+// the request's path
+const loggedPathKey = "logged-path"
+
+// unmatchedRouteKey - where the middleware stores whether the request didn't match a route (see isUnmatchedRoute())
+const unmatchedRouteKey = "unmatched-route"
+
+// fullPather - gin.Context has FullPath() since gin v1.5, so it's used when the module is built with a newer gin
+type fullPather interface {
+	FullPath() string
+}
+
+// RouteTemplate - the gin route template matched by the request (e.g. /users/:id), or "" when the request didn't match
+// a route (so the paths of 404s don't end up in the logs as routes).  It's c.FullPath() when gin has it (v1.5 or later),
+// otherwise it's rebuilt from the request path and the route parameters (c.Params), which is a best effort when a
+// parameter's value is the same as a static part of the route
+func RouteTemplate(c *gin.Context) string {
+	if fp, ok := interface{}(c).(fullPather); ok {
+		return fp.FullPath()
+	}
+	if isUnmatchedRoute(c) {
+		return ""
+	}
+	return replaceParams(c.Request.URL.Path, c.Params, func(p gin.Param) (string, bool) {
+		if strings.HasPrefix(p.Value, "/") {
+			return "*" + p.Key, true
+		}
+		return ":" + p.Key, true
+	})
+}
+
+// isUnmatchedRoute - did the request match no route.  Before gin v1.5 there's no c.FullPath(), but gin sets the 404 (or
+// 405) status of an unmatched request before its handlers run, and a route without params has no c.Params.  The
+// middleware stores the answer before the handlers can set the status themselves
+func isUnmatchedRoute(c *gin.Context) bool {
+	if unmatched, found := c.Get(unmatchedRouteKey); found {
+		return unmatched == true
+	}
+	status := c.Writer.Status()
+	return len(c.Params) == 0 && (status == http.StatusNotFound || status == http.StatusMethodNotAllowed)
+}
+
+// replaceParams - replace the values of the route parameters in the path with replace(p), when it returns true
+func replaceParams(path string, params gin.Params, replace func(p gin.Param) (string, bool)) string {
+	if len(params) == 0 {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	end := len(segments)
+	// the params are in route order, so match them from the end of the path
+	for i := len(params) - 1; i >= 0; i-- {
+		p := params[i]
+		if strings.HasPrefix(p.Value, "/") {
+			// a catch-all param has the rest of the path
+			if !strings.HasSuffix(path, p.Value) {
+				continue
+			}
+			n := strings.Count(p.Value, "/")
+			end = len(segments) - n
+			if r, ok := replace(p); ok {
+				segments = append(segments[:end], r)
+			}
+			continue
+		}
+		for j := end - 1; j >= 0; j-- {
+			if segments[j] == p.Value {
+				if r, ok := replace(p); ok {
+					segments[j] = r
+				}
+				end = j
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// routeCapture - captures the route template, the route parameters and the handler name for the request summary
+type routeCapture struct {
+	template bool
+	params   bool
+	handler  bool
+	redacted map[string]bool
+	redactor *Redactor
+}
+
+// newRouteCapture - a routeCapture for the options, or nil when nothing is captured or redacted
+func newRouteCapture(o options) *routeCapture {
+	if !o.routeTemplate && !o.routeParams && !o.handlerName && len(o.redactedRouteParams) == 0 && o.redactor == nil {
+		return nil
+	}
+	rc := &routeCapture{template: o.routeTemplate, params: o.routeParams, handler: o.handlerName, redacted: map[string]bool{},
+		redactor: o.redactor}
+	for _, p := range o.redactedRouteParams {
+		rc.redacted[p] = true
+	}
+	return rc
+}
+
+// fields - the "route", "route-params" and "handler" fields for the request
+func (rc *routeCapture) fields(c *gin.Context) logrus.Fields {
+	fields := logrus.Fields{}
+	if rc.template {
+		fields["route"] = RouteTemplate(c)
+	}
+	if rc.params && len(c.Params) != 0 {
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			if rc.redacted[p.Key] {
+				params[p.Key] = RedactedValue
+				continue
+			}
+			params[p.Key] = p.Value
+		}
+		fields["route-params"] = params
+	}
+	if rc.handler {
+		fields["handler"] = c.HandlerName()
+	}
+	return fields
+}
+
+// path - the request path with the values of the redacted route parameters (WithRedactedRouteParams() or the
+// redactor's rules) replaced, so they don't leak through the "path" field
+func (rc *routeCapture) path(c *gin.Context) string {
+	return replaceParams(c.Request.URL.Path, c.Params, func(p gin.Param) (string, bool) {
+		if rc.redacted[p.Key] {
+			return RedactedValue, true
+		}
+		if rc.redactor == nil {
+			return "", false
+		}
+		v, keep := rc.redactor.Value(p.Key, strings.TrimPrefix(p.Value, "/"))
+		if !keep {
+			return RedactedValue, true
+		}
+		if s, ok := v.(string); ok && s != strings.TrimPrefix(p.Value, "/") {
+			return s, true
+		}
+		return "", false
+	})
+}
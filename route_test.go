@@ -0,0 +1,219 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	tests := []struct {
+		route string
+		path  string
+	}{
+		{"/", "/"},
+		{"/health", "/health"},
+		{"/users/:id", "/users/123"},
+		{"/users/:id/orders/:order", "/users/123/orders/456"},
+		{"/a/:x", "/a/a"},
+		{"/files/*path", "/files/a/b/c.txt"},
+		{"/users/:id/files/*path", "/users/1/files/1/2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			var got string
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.GET(tt.route, func(c *gin.Context) {
+				got = RouteTemplate(c)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+			if got != tt.route {
+				t.Errorf("RouteTemplate() = %v, want %v", got, tt.route)
+			}
+		})
+	}
+}
+
+func TestRouteCapture(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want logrus.Fields
+	}{
+		{"none", nil, nil},
+		{"template", []Option{WithRouteTemplate(true)}, logrus.Fields{"route": "/users/:id/orders/:order"}},
+		{"params", []Option{WithRouteParams(true)}, logrus.Fields{"route-params": map[string]string{"id": "alice@example.com", "order": "42"}}},
+		{"redacted-params", []Option{WithRouteParams(true), WithRedactedRouteParams("id")}, logrus.Fields{"route-params": map[string]string{"id": RedactedValue, "order": "42"}}},
+		{"handler", []Option{WithHandlerName(true)}, logrus.Fields{"handler": "github.com/Bose/go-gin-logrus/v2.routeHandler"}},
+		{"all", []Option{WithRouteTemplate(true), WithRouteParams(true), WithHandlerName(true)}, logrus.Fields{
+			"route":        "/users/:id/orders/:order",
+			"route-params": map[string]string{"id": "alice@example.com", "order": "42"},
+			"handler":      "github.com/Bose/go-gin-logrus/v2.routeHandler",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions
+			for _, o := range tt.opts {
+				o(&opts)
+			}
+			rc := newRouteCapture(opts)
+			if tt.want == nil {
+				if rc != nil {
+					t.Error("newRouteCapture() = non-nil, want nil")
+				}
+				return
+			}
+			var got logrus.Fields
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				got = rc.fields(c)
+			})
+			r.GET("/users/:id/orders/:order", routeHandler)
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/alice@example.com/orders/42", nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func routeHandler(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func TestWithRouteTemplate(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(&out), WithRouteTemplate(true), WithRouteParams(true), WithHandlerName(true),
+		WithRedactor(NewRedactor(RedactKey("email", RedactMask)))))
+	r.GET("/users/:email", routeHandler)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/alice@example.com", nil))
+	var aggregate struct {
+		Summary map[string]interface{} `json:"request-summary-info"`
+	}
+	is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+	is.Equal(aggregate.Summary["path"], "/users/"+RedactedValue) // redacted by the key rule
+	is.Equal(aggregate.Summary["route"], "/users/:email")
+	is.Equal(aggregate.Summary["route-params"], map[string]interface{}{"email": RedactedValue}) // redacted by the key rule
+	is.Equal(aggregate.Summary["handler"], "github.com/Bose/go-gin-logrus/v2.routeHandler")
+}
+
+func TestWithRouteTemplateUnmatched(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		wantRoute string
+	}{
+		{"GET", "/users/1", "/users/:id"},
+		{"GET", "/health", "/health"}, // the handler's 404 doesn't make it unmatched
+		{"GET", "/nope/123", ""},
+		{"POST", "/health", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			is := is.New(t)
+			var out bytes.Buffer
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(New(WithAggregateLogging(true), WithWriter(&out), WithRouteTemplate(true)))
+			r.GET("/users/:id", routeHandler)
+			r.GET("/health", func(c *gin.Context) {
+				c.Status(http.StatusNotFound)
+			})
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			var aggregate struct {
+				Summary map[string]interface{} `json:"request-summary-info"`
+			}
+			is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+			is.Equal(aggregate.Summary["route"], tt.wantRoute)
+		})
+	}
+}
+
+func TestRouteCapture_path(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		route  string
+		target string
+		want   string
+	}{
+		{"not-redacted", []Option{WithRouteParams(true)}, "/users/:id/orders/:order", "/users/alice/orders/42", "/users/alice/orders/42"},
+		{"redacted-param", []Option{WithRedactedRouteParams("id")}, "/users/:id/orders/:order", "/users/alice/orders/42", "/users/" + RedactedValue + "/orders/42"},
+		{"same-as-static", []Option{WithRedactedRouteParams("id")}, "/users/:id", "/users/users", "/users/" + RedactedValue},
+		{"catch-all", []Option{WithRedactedRouteParams("file")}, "/files/*file", "/files/a/b.txt", "/files/" + RedactedValue},
+		{"redactor-key", []Option{WithRedactor(NewRedactor(RedactKey("order", RedactDrop)))}, "/users/:id/orders/:order", "/users/alice/orders/42", "/users/alice/orders/" + RedactedValue},
+		{"redactor-value", []Option{WithRedactor(NewRedactor(RedactEmails(RedactMask)))}, "/users/:id", "/users/alice@example.com", "/users/" + RedactedValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions
+			for _, o := range tt.opts {
+				o(&opts)
+			}
+			rc := newRouteCapture(opts)
+			var got string
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				got = rc.path(c)
+			})
+			r.GET(tt.route, routeHandler)
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.target, nil))
+			if got != tt.want {
+				t.Errorf("path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRedactedRouteParams(t *testing.T) {
+	tests := []struct {
+		name             string
+		aggregateLogging bool
+	}{
+		{"aggregate", true},
+		{"not-aggregate", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out, std bytes.Buffer
+			stdOut := logrus.StandardLogger().Out
+			logrus.SetOutput(&std)
+			defer logrus.SetOutput(stdOut)
+			logger := logrus.New()
+			logger.Out = &out
+
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Use(New(WithAggregateLogging(tt.aggregateLogging), WithWriter(&out), WithLogger(logger), WithRouteTemplate(true),
+				WithRouteParams(true), WithRedactedRouteParams("email")))
+			r.GET("/users/:email", func(c *gin.Context) {
+				GetCtxLogger(c).Info("found the user")
+				c.Status(http.StatusOK)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/alice@example.com", nil))
+
+			logged := out.String() + std.String()
+			is.True(strings.Contains(logged, "found the user"))
+			is.True(strings.Contains(logged, RedactedValue))
+			is.True(!strings.Contains(logged, "alice")) // not in the summary or the request's entries
+		})
+	}
+}
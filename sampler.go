@@ -0,0 +1,144 @@
+package ginlogrus
+
+import (
+	"math/rand"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sampler - decides if a request is logged when it's over, and the rate it was sampled at.  They're added to the
+// request summary as "sampled" and "sample_rate", so counts can be re-weighted
+type Sampler interface {
+	Sample(c *gin.Context, latency time.Duration) (sampled bool, rate float64)
+}
+
+// SamplerFunc - an adapter to allow the use of ordinary functions as a Sampler
+type SamplerFunc func(c *gin.Context, latency time.Duration) (bool, float64)
+
+// Sample - calls f(c, latency)
+func (f SamplerFunc) Sample(c *gin.Context, latency time.Duration) (bool, float64) {
+	return f(c, latency)
+}
+
+// randFloat64 - the random numbers used by the samplers (replaced by tests)
+var randFloat64 = rand.Float64
+
+// PercentageSampler - sample a fixed fraction of requests (e.g. 0.1 for 10%)
+func PercentageSampler(rate float64) Sampler {
+	return SamplerFunc(func(c *gin.Context, latency time.Duration) (bool, float64) {
+		return sample(rate), rate
+	})
+}
+
+// RouteSampler - sample a fixed fraction of requests by path, using defaultRate for paths that aren't found.  The
+// rates are keyed by an exact path (e.g. "/health") or a glob (e.g. "/api/v1/*"), and an exact path wins over a
+// glob (longer globs win over shorter ones)
+func RouteSampler(rates map[string]float64, defaultRate float64) Sampler {
+	globs := make([]string, 0, len(rates))
+	for p := range rates {
+		globs = append(globs, p)
+	}
+	sort.Slice(globs, func(i, j int) bool {
+		if len(globs[i]) != len(globs[j]) {
+			return len(globs[i]) > len(globs[j])
+		}
+		return globs[i] < globs[j]
+	})
+	return SamplerFunc(func(c *gin.Context, latency time.Duration) (bool, float64) {
+		rate := defaultRate
+		if r, found := rates[c.Request.URL.Path]; found {
+			rate = r
+		} else {
+			for _, g := range globs {
+				if matched, _ := path.Match(g, c.Request.URL.Path); matched {
+					rate = rates[g]
+					break
+				}
+			}
+		}
+		return sample(rate), rate
+	})
+}
+
+// rateLimitSampler - a token bucket, which also counts the requests seen to estimate the sample rate
+type rateLimitSampler struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	perSecond   float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	window      time.Time
+	seen        int
+	lastSeen    int
+	lastSampled int
+	sampled     int
+}
+
+// RateLimitSampler - sample at most perSecond requests a second (with bursts of up to burst), using a token bucket.
+// The sample rate is estimated from the requests sampled and seen in the previous second
+func RateLimitSampler(perSecond float64, burst int) Sampler {
+	return &rateLimitSampler{now: time.Now, perSecond: perSecond, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Sample - take a token from the bucket, if there is one
+func (s *rateLimitSampler) Sample(c *gin.Context, latency time.Duration) (bool, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if !s.last.IsZero() {
+		s.tokens += now.Sub(s.last).Seconds() * s.perSecond
+		if s.tokens > s.burst {
+			s.tokens = s.burst
+		}
+	}
+	s.last = now
+	if now.Sub(s.window) >= time.Second {
+		s.lastSeen, s.lastSampled = s.seen, s.sampled
+		s.seen, s.sampled = 0, 0
+		s.window = now
+	}
+	s.seen++
+	sampled := s.tokens >= 1
+	if sampled {
+		s.tokens--
+		s.sampled++
+	}
+	seen, kept := s.lastSeen, s.lastSampled
+	if seen == 0 {
+		// nothing from the last second yet, so use the current one
+		seen, kept = s.seen, s.sampled
+	}
+	rate := 1.0
+	if seen > 0 && kept < seen {
+		rate = float64(kept) / float64(seen)
+	}
+	return sampled, rate
+}
+
+// ErrorsAndSlowSampler - always sample errors (a 5xx status or gin errors) and requests slower than slow, and
+// use rest for everything else
+func ErrorsAndSlowSampler(slow time.Duration, rest Sampler) Sampler {
+	return SamplerFunc(func(c *gin.Context, latency time.Duration) (bool, float64) {
+		if c.Writer.Status() >= http.StatusInternalServerError || len(c.Errors) > 0 || (slow > 0 && latency >= slow) {
+			return true, 1
+		}
+		return rest.Sample(c, latency)
+	})
+}
+
+// sample - should a request be sampled at the rate
+func sample(rate float64) bool {
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+	return randFloat64() < rate
+}
@@ -0,0 +1,146 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+)
+
+func TestSamplers(t *testing.T) {
+	saved := randFloat64
+	randFloat64 = func() float64 { return 0.5 }
+	defer func() { randFloat64 = saved }()
+
+	routes := RouteSampler(map[string]float64{"/health": 0, "/api/*": 0.6, "/api/v1/*": 0.1}, 1)
+	tests := []struct {
+		name        string
+		sampler     Sampler
+		path        string
+		status      int
+		latency     time.Duration
+		wantSampled bool
+		wantRate    float64
+	}{
+		{"percentage-in", PercentageSampler(0.6), "/", 200, 0, true, 0.6},
+		{"percentage-out", PercentageSampler(0.1), "/", 200, 0, false, 0.1},
+		{"percentage-all", PercentageSampler(1), "/", 200, 0, true, 1},
+		{"route-exact", routes, "/health", 200, 0, false, 0},
+		{"route-longest-glob", routes, "/api/v1/things", 200, 0, false, 0.1},
+		{"route-glob", routes, "/api/things", 200, 0, true, 0.6},
+		{"route-default", routes, "/other", 200, 0, true, 1},
+		{"errors-and-slow-error", ErrorsAndSlowSampler(time.Second, PercentageSampler(0)), "/", 503, 0, true, 1},
+		{"errors-and-slow-slow", ErrorsAndSlowSampler(time.Second, PercentageSampler(0)), "/", 200, 2 * time.Second, true, 1},
+		{"errors-and-slow-rest", ErrorsAndSlowSampler(time.Second, PercentageSampler(0)), "/", 404, 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", tt.path, nil)
+			c.Status(tt.status)
+			c.Writer.WriteHeaderNow()
+			sampled, rate := tt.sampler.Sample(c, tt.latency)
+			if sampled != tt.wantSampled || rate != tt.wantRate {
+				t.Errorf("Sample() = %v, %v, want %v, %v", sampled, rate, tt.wantSampled, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestRateLimitSampler(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := RateLimitSampler(2, 2).(*rateLimitSampler)
+	s.now = func() time.Time { return now }
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	var got []bool
+	for i := 0; i < 4; i++ {
+		sampled, _ := s.Sample(c, 0)
+		got = append(got, sampled)
+	}
+	if want := []bool{true, true, false, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RateLimitSampler() = %v, want %v", got, want)
+	}
+
+	// a second later the bucket is full again, and the rate is estimated from the last second
+	now = now.Add(time.Second)
+	sampled, rate := s.Sample(c, 0)
+	if !sampled || rate != 0.5 {
+		t.Errorf("RateLimitSampler() = %v, %v, want true, 0.5", sampled, rate)
+	}
+}
+
+func TestWithSampler(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(&out), WithSampler(ErrorsAndSlowSampler(0, PercentageSampler(0)))))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(200, "Hello world!")
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.JSON(500, "Hello fail!")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	is.Equal(out.Len(), 0) // not sampled
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	var aggregate struct {
+		Summary map[string]interface{} `json:"request-summary-info"`
+	}
+	is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+	is.Equal(aggregate.Summary["status"], float64(http.StatusInternalServerError))
+	is.Equal(aggregate.Summary["sampled"], true)
+	is.Equal(aggregate.Summary["sample_rate"], float64(1))
+}
+
+func TestWithSamplerFailedRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		path   string
+		status int
+	}{
+		{"5xx", nil, "/fail", http.StatusServiceUnavailable},
+		{"tail-5xx", []Option{WithTailLogging(0)}, "/fail", http.StatusServiceUnavailable},
+		{"tail-slow", []Option{WithTailLogging(time.Millisecond)}, "/slow", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			var out bytes.Buffer
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			// the sampler never samples anything
+			r.Use(New(append(tt.opts, WithAggregateLogging(true), WithWriter(&out), WithSampler(PercentageSampler(0)))...))
+			r.GET("/fail", func(c *gin.Context) {
+				GetCtxLogger(c).Info("failing")
+				c.JSON(http.StatusServiceUnavailable, "Hello fail!")
+			})
+			r.GET("/slow", func(c *gin.Context) {
+				GetCtxLogger(c).Info("sleeping")
+				time.Sleep(5 * time.Millisecond)
+				c.JSON(http.StatusOK, "Hello slow!")
+			})
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+			var aggregate struct {
+				Summary map[string]interface{}   `json:"request-summary-info"`
+				Entries []map[string]interface{} `json:"entries"`
+			}
+			is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+			is.Equal(aggregate.Summary["status"], float64(tt.status))
+			is.Equal(aggregate.Summary["sampled"], true)
+			is.Equal(aggregate.Summary["sample_rate"], float64(1))
+			is.Equal(len(aggregate.Entries), 1) // with the entries
+		})
+	}
+}
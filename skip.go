@@ -0,0 +1,128 @@
+package ginlogrus
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// skipper - decides which requests aren't logged, by their exact path, path prefix, path regexp, route template or method
+type skipper struct {
+	paths    map[string]struct{}
+	prefixes []string
+	regexps  []*regexp.Regexp
+	routes   map[string]struct{}
+	methods  map[string]struct{}
+}
+
+// newSkipper - a skipper for the options, or nil when nothing is skipped
+func newSkipper(o options) *skipper {
+	if len(o.skipPaths)+len(o.skipPathPrefixes)+len(o.skipPathRegexps)+len(o.skipRoutes)+len(o.skipMethods) == 0 {
+		return nil
+	}
+	s := &skipper{
+		paths:    make(map[string]struct{}, len(o.skipPaths)),
+		prefixes: o.skipPathPrefixes,
+		regexps:  o.skipPathRegexps,
+		routes:   make(map[string]struct{}, len(o.skipRoutes)),
+		methods:  make(map[string]struct{}, len(o.skipMethods)),
+	}
+	for _, p := range o.skipPaths {
+		s.paths[p] = struct{}{}
+	}
+	for _, r := range o.skipRoutes {
+		s.routes[r] = struct{}{}
+	}
+	for _, m := range o.skipMethods {
+		s.methods[strings.ToUpper(m)] = struct{}{}
+	}
+	return s
+}
+
+// skip - is the request skipped
+func (s *skipper) skip(c *gin.Context) bool {
+	if _, found := s.methods[c.Request.Method]; found {
+		return true
+	}
+	path := c.Request.URL.Path
+	if _, found := s.paths[path]; found {
+		return true
+	}
+	for _, p := range s.prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	for _, re := range s.regexps {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	if len(s.routes) != 0 {
+		if _, found := s.routes[RouteTemplate(c)]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// skipCounter - counts the skipped requests by method and route template, so they're still visible.  The counts are
+// emitted by the first request after every interval (there's no background goroutine), and then they're reset
+type skipCounter struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	interval time.Duration
+	since    time.Time
+	counts   map[string]int
+}
+
+// newSkipCounter - a skipCounter which emits the counts every interval
+func newSkipCounter(interval time.Duration) *skipCounter {
+	return &skipCounter{
+		now:      time.Now,
+		interval: interval,
+		since:    time.Now(),
+		counts:   map[string]int{},
+	}
+}
+
+// add - count a skipped request
+func (s *skipCounter) add(c *gin.Context) {
+	key := c.Request.Method + " " + RouteTemplate(c)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[key]++
+}
+
+// due - the fields for the skipped requests since they were last emitted, when the interval is over and there were some
+func (s *skipCounter) due(utc bool, timeFormat string) (logrus.Fields, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.since) < s.interval {
+		return nil, false
+	}
+	since := s.since
+	s.since = now
+	if len(s.counts) == 0 {
+		return nil, false
+	}
+	counts, total := s.counts, 0
+	for _, n := range counts {
+		total += n
+	}
+	s.counts = make(map[string]int, len(counts))
+	if utc {
+		since, now = since.UTC(), now.UTC()
+	}
+	return logrus.Fields{
+		"skipped-requests": counts,
+		"skipped-total":    total,
+		"since":            since.Format(timeFormat),
+		"time":             now.Format(timeFormat),
+	}, true
+}
@@ -0,0 +1,159 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSkipper(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		method string
+		route  string
+		path   string
+		want   bool
+	}{
+		{"none", []Option{WithSkipPaths("/healthz")}, "GET", "/users/:id", "/users/1", false},
+		{"path", []Option{WithSkipPaths("/healthz", "/metrics")}, "GET", "/metrics", "/metrics", true},
+		{"path-not-prefix", []Option{WithSkipPaths("/metrics")}, "GET", "/metrics/cpu", "/metrics/cpu", false},
+		{"prefix", []Option{WithSkipPathPrefixes("/debug/")}, "GET", "/debug/pprof/*profile", "/debug/pprof/heap", true},
+		{"regexp", []Option{WithSkipPathRegexps(regexp.MustCompile(`^/v[0-9]+/ping$`))}, "GET", "/v2/ping", "/v2/ping", true},
+		{"route", []Option{WithSkipRoutes("/static/*filepath")}, "GET", "/static/*filepath", "/static/css/app.css", true},
+		{"route-param", []Option{WithSkipRoutes("/users/:id")}, "GET", "/users/:id", "/users/1", true},
+		{"method", []Option{WithSkipMethods("options")}, "OPTIONS", "/users/:id", "/users/1", true},
+		{"other-method", []Option{WithSkipMethods("HEAD")}, "GET", "/users/:id", "/users/1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions
+			for _, o := range tt.opts {
+				o(&opts)
+			}
+			s := newSkipper(opts)
+			var got bool
+			gin.SetMode(gin.DebugMode)
+			r := gin.New()
+			r.Handle(tt.method, tt.route, func(c *gin.Context) {
+				got = s.skip(c)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if got != tt.want {
+				t.Errorf("skip() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if newSkipper(defaultOptions) != nil {
+		t.Error("newSkipper() = non-nil, want nil without any skip options")
+	}
+}
+
+func TestSkipCounter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newSkipCounter(time.Minute)
+	s.now = func() time.Time { return now }
+	s.since = now
+
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.GET("/users/:id", s.add)
+	r.GET("/healthz", s.add)
+	for _, p := range []string{"/users/1", "/users/2", "/healthz"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", p, nil))
+	}
+	if _, due := s.due(true, time.RFC3339); due {
+		t.Error("due() = true, want false before the interval")
+	}
+
+	now = now.Add(time.Minute)
+	fields, due := s.due(true, time.RFC3339)
+	if !due {
+		t.Fatal("due() = false, want true after the interval")
+	}
+	want := map[string]int{"GET /users/:id": 2, "GET /healthz": 1}
+	got := fields["skipped-requests"].(map[string]int)
+	if len(got) != len(want) || got["GET /users/:id"] != 2 || got["GET /healthz"] != 1 || fields["skipped-total"] != 3 {
+		t.Errorf("due() = %v, want %v", fields, want)
+	}
+	if fields["since"] != "2020-01-01T00:00:00Z" || fields["time"] != "2020-01-01T00:01:00Z" {
+		t.Errorf("due() since/time = %v/%v", fields["since"], fields["time"])
+	}
+
+	// the counts were reset, so there's nothing to emit after the next interval
+	now = now.Add(time.Minute)
+	if _, due := s.due(true, time.RFC3339); due {
+		t.Error("due() = true, want false without any skipped requests")
+	}
+}
+
+func TestWithSkipPaths(t *testing.T) {
+	is := is.New(t)
+	var out, errs bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &errs
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithLogger(logger), WithAggregateLogging(true), WithWriter(&out), WithSkipPaths("/healthz", "/unhealthy"),
+		WithSkippedRequestCounter(time.Nanosecond)))
+	r.GET("/healthz", func(c *gin.Context) {
+		logger := GetCtxLogger(c)
+		logger.Info("healthy")
+		c.JSON(200, "ok")
+	})
+	r.GET("/unhealthy", func(c *gin.Context) {
+		_ = c.Error(errors.New("unhealthy"))
+		c.JSON(503, "unhealthy")
+	})
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(200, "Hello world!")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	var aggregate struct {
+		Skipped map[string]interface{} `json:"skipped-requests-info"`
+		Summary map[string]interface{} `json:"request-summary-info"`
+	}
+	is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+	is.Equal(aggregate.Summary, nil) // just the skipped requests
+	is.Equal(aggregate.Skipped["skipped-total"], float64(1))
+	is.Equal(aggregate.Skipped["skipped-requests"], map[string]interface{}{"GET /healthz": float64(1)})
+
+	out.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	aggregate.Skipped, aggregate.Summary = nil, nil
+	is.NoErr(json.Unmarshal(out.Bytes(), &aggregate))
+	is.Equal(aggregate.Summary["path"], "/") // not skipped, and no skipped requests since the last counts
+	is.Equal(aggregate.Skipped, nil)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unhealthy", nil))
+	is.True(bytes.Contains(errs.Bytes(), []byte("path=/unhealthy"))) // errors aren't skipped
+}
+
+func TestWithSkipMethodsNotAggregate(t *testing.T) {
+	is := is.New(t)
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &out
+	logger.Formatter = &logrus.JSONFormatter{}
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithLogger(logger), WithSkipMethods("HEAD"), WithSkippedRequestCounter(time.Nanosecond)))
+	r.HEAD("/", func(c *gin.Context) {
+		c.Status(200)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("HEAD", "/", nil))
+	var entry map[string]interface{}
+	is.NoErr(json.Unmarshal(out.Bytes(), &entry))
+	is.Equal(entry["msg"], "skipped requests")
+	is.Equal(entry["skipped-total"], float64(1))
+}
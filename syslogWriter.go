@@ -0,0 +1,279 @@
+package ginlogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SyslogFacility - the facility of a syslog message
+type SyslogFacility int
+
+// The syslog facilities from RFC 5424
+const (
+	SyslogKern SyslogFacility = iota
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLpr
+	SyslogNews
+	SyslogUucp
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFTP
+	// 12 to 15 are ntp, security, console and solaris-cron
+	SyslogLocal0 SyslogFacility = iota + 4
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// syslogSeverities - the syslog severity for each logrus.Level
+var syslogSeverities = map[logrus.Level]int{
+	logrus.PanicLevel: 0, // emergency
+	logrus.FatalLevel: 2, // critical
+	logrus.ErrorLevel: 3, // error
+	logrus.WarnLevel:  4, // warning
+	logrus.InfoLevel:  6, // informational
+	logrus.DebugLevel: 7, // debug
+	logrus.TraceLevel: 7,
+}
+
+// syslogTimeFormat - the RFC 5424 TIMESTAMP
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// SyslogWriter - an AggregateWriter which sends every aggregate as an RFC 5424 message over "udp", "tcp", "unix" or
+// "unixgram".  The severity comes from the level of the request summary (not its entries), the request id is the MSGID
+// (see syslogMsgID()) and the selected summary fields are the STRUCTURED-DATA.  Stream connections use octet-counted
+// framing (RFC 6587) and they're reconnected after an error.  Datagrams are cut to WithSyslogMaxMessageSize().  It's
+// safe for concurrent writers
+type SyslogWriter struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	opts    syslogOptions
+	conn    net.Conn
+}
+
+// NewSyslogWriter - create a SyslogWriter and connect to the syslog server at addr (e.g. "localhost:514" or "/dev/log")
+func NewSyslogWriter(network, addr string, opt ...SyslogOption) (*SyslogWriter, error) {
+	opts := defaultSyslogOptions()
+	for _, o := range opt {
+		o(&opts)
+	}
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	w := &SyslogWriter{network: network, addr: addr, opts: opts}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write - send p as a message.  When p is an aggregate encoded by the JSONEncoder its request summary is used,
+// otherwise the severity is informational and there's no MSGID
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	var a Aggregate
+	_ = json.Unmarshal(p, &a.Header)
+	if err := w.WriteAggregate(a, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteAggregate - send the encoded aggregate as a message, using the aggregate for the header and STRUCTURED-DATA
+func (w *SyslogWriter) WriteAggregate(a Aggregate, encoded []byte) error {
+	msg := w.format(a, encoded)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	err := w.send(msg)
+	if err != nil && w.isStream() {
+		// the server may have closed the connection, so reconnect and try again
+		w.conn.Close()
+		w.conn = nil
+		if err = w.connect(); err != nil {
+			return err
+		}
+		err = w.send(msg)
+	}
+	return err
+}
+
+// Close - close the connection to the syslog server
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// connect - connect to the syslog server, the caller must hold mu (or be the constructor)
+func (w *SyslogWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.addr, w.opts.dialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// isStream - does the network need octet-counted framing
+func (w *SyslogWriter) isStream() bool {
+	return strings.HasPrefix(w.network, "tcp") || w.network == "unix"
+}
+
+// send - write the message with the framing for the network, the caller must hold mu
+func (w *SyslogWriter) send(msg []byte) error {
+	if w.isStream() {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// isDatagram - is every message sent in one datagram
+func (w *SyslogWriter) isDatagram() bool {
+	return strings.HasPrefix(w.network, "udp") || w.network == "unixgram"
+}
+
+// format - the RFC 5424 message for the aggregate: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *SyslogWriter) format(a Aggregate, encoded []byte) []byte {
+	summary := a.Summary()
+	body := bytes.TrimSuffix(encoded, []byte("\n"))
+	msgID := "-"
+	if id, ok := summary[w.opts.requestIDField]; ok {
+		msgID = syslogMsgID(fmt.Sprint(id))
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "<%d>1 %s %s %s %d %s ",
+		int(w.opts.facility)*8+syslogSeverities[a.summaryLevel()],
+		a.Time().Format(syslogTimeFormat),
+		syslogHeaderField(w.opts.hostname, 255),
+		syslogHeaderField(w.opts.appName, 48),
+		os.Getpid(),
+		msgID)
+	sd := w.structuredData(summary, false)
+	if max := w.opts.maxMessageSize; max > 0 && w.isDatagram() && msg.Len()+len(sd)+1+len(body) > max {
+		// the message has to fit in one datagram (RFC 5426), so the MSG is cut and the STRUCTURED-DATA says so
+		sd = w.structuredData(summary, true)
+		if msg.Len()+len(sd)+1 > max {
+			// the selected fields don't fit either, so they're dropped too
+			sd = w.structuredData(nil, true)
+		}
+		body = truncateUTF8(body, max-msg.Len()-len(sd)-1)
+	}
+	msg.WriteString(sd)
+	msg.WriteString(" ")
+	msg.Write(body)
+	if max := w.opts.maxMessageSize; max > 0 && w.isDatagram() {
+		// when even the HEADER doesn't fit, the datagram is cut anyway so it isn't rejected
+		return truncateUTF8(msg.Bytes(), max)
+	}
+	return msg.Bytes()
+}
+
+// truncateUTF8 - the first n bytes (at most) of b, without cutting a UTF-8 character in half
+func truncateUTF8(b []byte, n int) []byte {
+	if n >= len(b) {
+		return b
+	}
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	return b[:n]
+}
+
+// structuredData - the STRUCTURED-DATA element with the selected summary fields (and truncated="true" when the MSG was
+// cut), or "-" when there aren't any
+func (w *SyslogWriter) structuredData(summary logrus.Fields, truncated bool) string {
+	var sd strings.Builder
+	for _, name := range w.opts.structuredFields {
+		v, ok := summary[name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&sd, " %s=\"%s\"", syslogSDName(name), syslogSDValue(fmt.Sprint(v)))
+	}
+	if truncated {
+		sd.WriteString(` truncated="true"`)
+	}
+	if sd.Len() == 0 {
+		return "-"
+	}
+	return "[" + syslogSDName(w.opts.sdID) + sd.String() + "]"
+}
+
+// syslogHeaderField - a header field is 1 to max printable US-ASCII characters, or "-"
+func syslogHeaderField(s string, max int) string {
+	var b strings.Builder
+	for i := 0; i < len(s) && b.Len() < max; i++ {
+		if s[i] > ' ' && s[i] < 0x7f {
+			b.WriteByte(s[i])
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// syslogMsgID - the request id as a MSGID, which is 1 to 32 printable US-ASCII characters.  When the id is too long its
+// dashes are dropped (so a UUID fits), and when it's still too long it's "-" rather than cut, since cutting it would
+// make a different id (the whole id is still in the STRUCTURED-DATA with the default fields)
+func syslogMsgID(id string) string {
+	if msgID := syslogHeaderField(id, 33); len(msgID) <= 32 {
+		return msgID
+	}
+	if msgID := syslogHeaderField(strings.Replace(id, "-", "", -1), 33); len(msgID) <= 32 {
+		return msgID
+	}
+	return "-"
+}
+
+// syslogSDName - an SD-NAME is 1 to 32 printable US-ASCII characters, except '=', ' ', ']' and '"'
+func syslogSDName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s) && b.Len() < 32; i++ {
+		if c := s[i]; c > ' ' && c < 0x7f && c != '=' && c != ']' && c != '"' {
+			b.WriteByte(c)
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// syslogSDValue - a PARAM-VALUE with '"', '\' and ']' escaped
+func syslogSDValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
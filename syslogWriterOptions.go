@@ -0,0 +1,107 @@
+package ginlogrus
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SyslogOption - define options for NewSyslogWriter()
+type SyslogOption func(*syslogOptions)
+type syslogOptions struct {
+	facility         SyslogFacility
+	hostname         string
+	appName          string
+	sdID             string
+	structuredFields []string
+	requestIDField   string
+	dialTimeout      time.Duration
+	maxMessageSize   int
+}
+
+// DefaultSyslogSDID - the default SD-ID of the STRUCTURED-DATA element (32473 is the private enterprise number reserved
+// for documentation by RFC 5612, so use your own in production)
+const DefaultSyslogSDID = "gin@32473"
+
+// DefaultSyslogMaxMessageSize - the default size limit of a message sent as a datagram ("udp" and "unixgram"), which is
+// the size RFC 5426 says receivers should support
+const DefaultSyslogMaxMessageSize = 2048
+
+// DefaultSyslogStructuredFields - the request summary fields added to the STRUCTURED-DATA by default
+var DefaultSyslogStructuredFields = []string{"requestID", "method", "path", "status", "latency-ms"}
+
+func defaultSyslogOptions() syslogOptions {
+	hostname, _ := os.Hostname()
+	return syslogOptions{
+		facility:         SyslogUser,
+		hostname:         hostname,
+		appName:          filepath.Base(os.Args[0]),
+		sdID:             DefaultSyslogSDID,
+		structuredFields: DefaultSyslogStructuredFields,
+		requestIDField:   "requestID",
+		dialTimeout:      5 * time.Second,
+		maxMessageSize:   DefaultSyslogMaxMessageSize,
+	}
+}
+
+// WithSyslogFacility - define an Option func for the facility of the messages, the default is SyslogUser
+func WithSyslogFacility(f SyslogFacility) SyslogOption {
+	return func(o *syslogOptions) {
+		o.facility = f
+	}
+}
+
+// WithSyslogHostname - define an Option func for the HOSTNAME of the messages, the default is os.Hostname()
+func WithSyslogHostname(h string) SyslogOption {
+	return func(o *syslogOptions) {
+		o.hostname = h
+	}
+}
+
+// WithSyslogAppName - define an Option func for the APP-NAME of the messages, the default is the name of the executable
+func WithSyslogAppName(a string) SyslogOption {
+	return func(o *syslogOptions) {
+		o.appName = a
+	}
+}
+
+// WithSyslogSDID - define an Option func for the SD-ID of the STRUCTURED-DATA element (e.g. "gin@<your enterprise
+// number>"), the default is DefaultSyslogSDID
+func WithSyslogSDID(id string) SyslogOption {
+	return func(o *syslogOptions) {
+		o.sdID = id
+	}
+}
+
+// WithSyslogStructuredFields - define an Option func for the request summary fields added to the STRUCTURED-DATA, the
+// default is DefaultSyslogStructuredFields
+func WithSyslogStructuredFields(names ...string) SyslogOption {
+	return func(o *syslogOptions) {
+		o.structuredFields = names
+	}
+}
+
+// WithSyslogRequestIDField - define an Option func for the request summary field used as the MSGID, which should match
+// WithTraceIDFieldName().  The default is "requestID"
+func WithSyslogRequestIDField(name string) SyslogOption {
+	return func(o *syslogOptions) {
+		o.requestIDField = name
+	}
+}
+
+// WithSyslogDialTimeout - define an Option func for the timeout when connecting to the syslog server, the default is 5s
+func WithSyslogDialTimeout(d time.Duration) SyslogOption {
+	return func(o *syslogOptions) {
+		o.dialTimeout = d
+	}
+}
+
+// WithSyslogMaxMessageSize - define an Option func for the size limit (in bytes) of a message sent as a datagram ("udp"
+// and "unixgram").  A longer MSG is cut, and truncated="true" is added to the STRUCTURED-DATA (the selected fields are
+// dropped when they don't fit either).  Zero means no limit, and the default is DefaultSyslogMaxMessageSize.  Messages
+// over a stream connection are never cut
+func WithSyslogMaxMessageSize(n int) SyslogOption {
+	return func(o *syslogOptions) {
+		o.maxMessageSize = n
+	}
+}
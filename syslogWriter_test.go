@@ -0,0 +1,353 @@
+package ginlogrus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/matryer/is"
+	"github.com/sirupsen/logrus"
+)
+
+// syslogListener - a local syslog server, which sends every message it gets to msgs
+type syslogListener struct {
+	network string
+	addr    string
+	msgs    chan string
+	close   func()
+}
+
+func newSyslogListener(t *testing.T, network string) *syslogListener {
+	t.Helper()
+	l := &syslogListener{network: network, msgs: make(chan string, 10)}
+	switch network {
+	case "udp", "unixgram":
+		addr := "127.0.0.1:0"
+		if network == "unixgram" {
+			addr = filepath.Join(t.TempDir(), "syslog.sock")
+		}
+		conn, err := net.ListenPacket(network, addr)
+		if err != nil {
+			t.Skipf("can't listen on %s: %v", network, err)
+		}
+		l.addr, l.close = conn.LocalAddr().String(), func() { conn.Close() }
+		go func() {
+			buf := make([]byte, 65536)
+			for {
+				n, _, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				l.msgs <- string(buf[:n])
+			}
+		}()
+	case "tcp", "unix":
+		addr := "127.0.0.1:0"
+		if network == "unix" {
+			addr = filepath.Join(t.TempDir(), "syslog.sock")
+		}
+		ln, err := net.Listen(network, addr)
+		if err != nil {
+			t.Skipf("can't listen on %s: %v", network, err)
+		}
+		l.addr, l.close = ln.Addr().String(), func() { ln.Close() }
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go readOctetCounted(conn, l.msgs)
+			}
+		}()
+	}
+	t.Cleanup(l.close)
+	return l
+}
+
+// readOctetCounted - read "LEN SP MSG" frames from the connection
+func readOctetCounted(conn net.Conn, msgs chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			msgs <- "bad frame: " + length
+			return
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		msgs <- string(msg)
+	}
+}
+
+func (l *syslogListener) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case m := <-l.msgs:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no syslog message was received")
+	}
+	return ""
+}
+
+// testSyslogAggregate - an aggregate for a request that failed
+func testSyslogAggregate() (Aggregate, []byte) {
+	a := Aggregate{
+		Header: map[string]interface{}{
+			"request-summary-info": logrus.Fields{
+				"requestID":  "4bf92f3577b34da6a3ce929d0e0e4736",
+				"method":     "GET",
+				"path":       `/a"b]c\d`,
+				"status":     503,
+				"latency-ms": 1.5,
+				"time":       "2020-01-01T00:00:00Z",
+			},
+		},
+		SummaryTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	return a, []byte(`{"request-summary-info":{"status":503},"entries":[]}` + "\n")
+}
+
+func TestSyslogWriter(t *testing.T) {
+	want := fmt.Sprintf(`<11>1 2020-01-01T00:00:00.000000Z my-host my-app %d 4bf92f3577b34da6a3ce929d0e0e4736 `, os.Getpid()) +
+		`[gin@32473 requestID="4bf92f3577b34da6a3ce929d0e0e4736" method="GET" path="/a\"b\]c\\d" status="503" latency-ms="1.5"] ` +
+		`{"request-summary-info":{"status":503},"entries":[]}`
+	for _, network := range []string{"udp", "tcp", "unix", "unixgram"} {
+		t.Run(network, func(t *testing.T) {
+			l := newSyslogListener(t, network)
+			w, err := NewSyslogWriter(network, l.addr, WithSyslogHostname("my-host"), WithSyslogAppName("my-app"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			a, encoded := testSyslogAggregate()
+			for i := 0; i < 2; i++ {
+				if err := w.WriteAggregate(a, encoded); err != nil {
+					t.Fatal(err)
+				}
+				if got := l.next(t); got != want {
+					t.Errorf("message = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyslogWriterFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []SyslogOption
+		header  map[string]interface{}
+		entries []LogEntry
+		want    string
+	}{
+		{
+			name: "no-summary",
+			header: map[string]interface{}{
+				"skipped-requests-info": map[string]interface{}{"skipped-total": 1},
+			},
+			want: `^<14>1 \S+ h app \d+ - - msg$`,
+		},
+		{
+			name:   "facility-and-level",
+			opts:   []SyslogOption{WithSyslogFacility(SyslogLocal0)},
+			header: map[string]interface{}{"request-summary-info": logrus.Fields{"status": 200, "level": "warning"}},
+			want:   `^<132>1 \S+ h app \d+ - \[gin@32473 status="200"\] msg$`,
+		},
+		{
+			name:    "summary-level",
+			header:  map[string]interface{}{"request-summary-info": logrus.Fields{"status": 200}},
+			entries: []LogEntry{{Level: logrus.ErrorLevel, Message: "handled"}},
+			want:    `^<14>1 \S+ h app \d+ - \[gin@32473 status="200"\] msg$`, // the entries don't change the severity
+		},
+		{
+			name:   "long-request-id",
+			opts:   []SyslogOption{WithSyslogStructuredFields("requestID"), WithSyslogSDID("req@1")},
+			header: map[string]interface{}{"request-summary-info": logrus.Fields{"requestID": "f81d4fae-7dec-11d0-a765-00a0c91e6bf6"}},
+			want:   `^<14>1 \S+ h app \d+ f81d4fae7dec11d0a76500a0c91e6bf6 \[req@1 requestID="f81d4fae-7dec-11d0-a765-00a0c91e6bf6"\] msg$`,
+		},
+		{
+			name:   "too-long-request-id",
+			opts:   []SyslogOption{WithSyslogStructuredFields("requestID")},
+			header: map[string]interface{}{"request-summary-info": logrus.Fields{"requestID": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			want:   `^<14>1 \S+ h app \d+ - \[gin@32473 requestID="00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"\] msg$`,
+		},
+		{
+			name:   "request-id-field",
+			opts:   []SyslogOption{WithSyslogRequestIDField("trace"), WithSyslogStructuredFields()},
+			header: map[string]interface{}{"request-summary-info": logrus.Fields{"trace": "abc def"}},
+			want:   `^<14>1 \S+ h app \d+ abcdef - msg$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultSyslogOptions()
+			for _, o := range append([]SyslogOption{WithSyslogHostname("h"), WithSyslogAppName("app")}, tt.opts...) {
+				o(&opts)
+			}
+			w := &SyslogWriter{opts: opts}
+			got := string(w.format(Aggregate{Header: tt.header, Entries: tt.entries}, []byte("msg\n")))
+			if !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("format() = %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogWriterMaxMessageSize(t *testing.T) {
+	a, _ := testSyslogAggregate()
+	tests := []struct {
+		network       string
+		opts          []SyslogOption
+		repeat        int
+		wantMax       int
+		wantTruncated bool
+	}{
+		{"udp", nil, 20000, DefaultSyslogMaxMessageSize, true},
+		{"udp", []SyslogOption{WithSyslogMaxMessageSize(8192)}, 20000, 8192, true},
+		{"unixgram", []SyslogOption{WithSyslogMaxMessageSize(512)}, 20000, 512, true},
+		{"udp", nil, 100, 0, false}, // it fits
+		{"udp", []SyslogOption{WithSyslogMaxMessageSize(0)}, 1000, 0, false},
+		{"tcp", nil, 20000, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%d-%d", tt.network, tt.repeat, tt.wantMax), func(t *testing.T) {
+			is := is.New(t)
+			// an aggregate with multi-byte characters
+			encoded := []byte(strings.Repeat("héllo ", tt.repeat) + "\n")
+			l := newSyslogListener(t, tt.network)
+			w, err := NewSyslogWriter(tt.network, l.addr, tt.opts...)
+			is.NoErr(err)
+			defer w.Close()
+			is.NoErr(w.WriteAggregate(a, encoded))
+			msg := l.next(t)
+
+			is.True(utf8.ValidString(msg))
+			is.Equal(strings.Contains(msg, ` truncated="true"]`), tt.wantTruncated)
+			if tt.wantMax != 0 {
+				is.True(len(msg) <= tt.wantMax)
+				is.True(len(msg) > tt.wantMax-len("é")) // as much of the MSG as fits
+				is.True(strings.Contains(msg, `] héllo héllo`))
+				return
+			}
+			is.True(strings.HasSuffix(msg, string(bytes.TrimSuffix(encoded, []byte("\n")))))
+		})
+	}
+}
+
+func TestSyslogWriterMaxMessageSizeStructuredData(t *testing.T) {
+	// the path alone is bigger than the datagram
+	header := map[string]interface{}{"request-summary-info": logrus.Fields{"path": "/" + strings.Repeat("a", 600)}}
+	tests := []struct {
+		name string
+		max  int
+		want string
+	}{
+		{"fields-dropped", 512, `^<14>1 \S+ h app \d+ - \[gin@32473 truncated="true"\] msg$`},
+		{"header-cut", 20, `^<14>1 \S+$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultSyslogOptions()
+			for _, o := range []SyslogOption{WithSyslogHostname("h"), WithSyslogAppName("app"), WithSyslogMaxMessageSize(tt.max)} {
+				o(&opts)
+			}
+			w := &SyslogWriter{network: "udp", opts: opts}
+			got := string(w.format(Aggregate{Header: header}, []byte("msg\n")))
+			if len(got) > tt.max {
+				t.Errorf("len(format()) = %d, want <= %d", len(got), tt.max)
+			}
+			if !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("format() = %q, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogWriterReconnects(t *testing.T) {
+	is := is.New(t)
+	l := newSyslogListener(t, "tcp")
+	w, err := NewSyslogWriter("tcp", l.addr)
+	is.NoErr(err)
+	defer w.Close()
+
+	// the connection is gone, so the next write reconnects
+	w.conn.Close()
+	_, err = w.Write([]byte("hello\n"))
+	is.NoErr(err)
+	is.True(strings.HasSuffix(l.next(t), " - - hello"))
+
+	_, err = NewSyslogWriter("ip", l.addr)
+	is.True(err != nil) // unsupported network
+}
+
+func TestSyslogWriterWithMiddleware(t *testing.T) {
+	is := is.New(t)
+	l := newSyslogListener(t, "udp")
+	w, err := NewSyslogWriter("udp", l.addr)
+	is.NoErr(err)
+	defer w.Close()
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	r.Use(New(WithAggregateLogging(true), WithWriter(w), WithAggregateEncoder(LogfmtEncoder()),
+		WithIDGenerator(IDGeneratorFunc(func() string { return "my-request-id" }))))
+	r.GET("/fail", func(c *gin.Context) {
+		c.JSON(500, "Hello fail!")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	msg := l.next(t)
+	is.True(strings.HasPrefix(msg, "<11>1 ")) // user.err for the 500
+	is.True(strings.Contains(msg, " my-request-id [gin@32473 requestID=\"my-request-id\""))
+	is.True(bytes.Contains([]byte(msg), []byte("request-summary-info.status=500"))) // the message is the encoded aggregate
+}
+
+func TestSyslogWriterWithAsyncWriter(t *testing.T) {
+	is := is.New(t)
+	l := newSyslogListener(t, "tcp")
+	sw, err := NewSyslogWriter("tcp", l.addr)
+	is.NoErr(err)
+	defer sw.Close()
+	w := NewAsyncWriter(sw, WithBatchSize(10), WithFlushInterval(time.Hour))
+	gin.SetMode(gin.DebugMode)
+	r := gin.New()
+	ids := 0
+	r.Use(New(WithAggregateLogging(true), WithWriter(w),
+		WithIDGenerator(IDGeneratorFunc(func() string { ids++; return fmt.Sprintf("request-%d", ids) }))))
+	r.GET("/fail", func(c *gin.Context) {
+		c.JSON(500, "Hello fail!")
+	})
+
+	for i := 0; i < 2; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	}
+	is.NoErr(w.Close(context.Background()))
+	// one message per aggregate, each with its own header
+	for i := 1; i <= 2; i++ {
+		msg := l.next(t)
+		is.True(strings.HasPrefix(msg, "<11>1 "))
+		is.True(strings.Contains(msg, fmt.Sprintf(" request-%d [gin@32473 requestID=\"request-%d\"", i, i)))
+		is.Equal(strings.Count(msg, `"request-summary-info"`), 1)
+	}
+}
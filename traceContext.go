@@ -0,0 +1,147 @@
+package ginlogrus
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// TraceParentHeader - the W3C Trace Context request header with the trace-id, parent span-id and flags
+	TraceParentHeader = "traceparent"
+	// TraceStateHeader - the W3C Trace Context request header with vendor specific trace info
+	TraceStateHeader = "tracestate"
+	// TraceResponseHeader - the W3C Trace Context response header that's echoed back to the caller
+	TraceResponseHeader = "traceresponse"
+
+	// traceContextKey - where the parsed TraceContext is stored in the gin.Context
+	traceContextKey = "trace-context"
+)
+
+// TraceContext - the W3C Trace Context (https://www.w3.org/TR/trace-context/) from the traceparent and tracestate headers
+type TraceContext struct {
+	Version  string
+	TraceID  string
+	ParentID string
+	// SpanID - the span-id of this server's span, which is echoed back to the caller in the traceresponse header and
+	// is the parent-id of the outbound requests.  It's only set by the middleware when WithTraceResponse() is used
+	SpanID     string
+	Flags      byte
+	TraceState string
+}
+
+// ParseTraceParent - parse a W3C traceparent header (e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01)
+func ParseTraceParent(traceParent string) (TraceContext, error) {
+	tp := strings.TrimSpace(traceParent)
+	if len(tp) < 55 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: too short", traceParent)
+	}
+	version := tp[0:2]
+	if !isLowerHex(version) || version == "ff" {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: bad version", traceParent)
+	}
+	// future versions may add fields after the flags, but version 00 can't
+	if (version == "00" && len(tp) != 55) || (len(tp) > 55 && tp[55] != '-') {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: bad length", traceParent)
+	}
+	if tp[2] != '-' || tp[35] != '-' || tp[52] != '-' {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: bad delimiter", traceParent)
+	}
+	tc := TraceContext{
+		Version:  version,
+		TraceID:  tp[3:35],
+		ParentID: tp[36:52],
+	}
+	if !isLowerHex(tc.TraceID) || strings.Trim(tc.TraceID, "0") == "" {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: bad trace-id", traceParent)
+	}
+	if !isLowerHex(tc.ParentID) || strings.Trim(tc.ParentID, "0") == "" {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: bad parent-id", traceParent)
+	}
+	flags := tp[53:55]
+	if !isLowerHex(flags) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: bad flags", traceParent)
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+	tc.Flags = byte(f)
+	return tc, nil
+}
+
+// CxtTraceContext - get the W3C TraceContext for the request.  It's parsed from the traceparent and tracestate headers
+// the first time, and then it's stored in the gin.Context
+func CxtTraceContext(c *gin.Context) (TraceContext, bool) {
+	if tc, found := c.Get(traceContextKey); found {
+		t, ok := tc.(TraceContext)
+		return t, ok
+	}
+	tc, err := ParseTraceParent(c.Request.Header.Get(TraceParentHeader))
+	if err != nil {
+		return TraceContext{}, false
+	}
+	// tracestate is only valid with a valid traceparent
+	tc.TraceState = strings.Join(c.Request.Header[http.CanonicalHeaderKey(TraceStateHeader)], ",")
+	c.Set(traceContextKey, tc)
+	return tc, true
+}
+
+// Sampled - is the sampled flag set
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&0x01 == 0x01
+}
+
+// Fields - the logrus.Fields for the trace context
+func (tc TraceContext) Fields() logrus.Fields {
+	fields := logrus.Fields{
+		"trace-id":      tc.TraceID,
+		"parent-id":     tc.ParentID,
+		"trace-sampled": tc.Sampled(),
+	}
+	if len(tc.SpanID) != 0 {
+		fields["span-id"] = tc.SpanID
+	}
+	if len(tc.TraceState) != 0 {
+		fields["tracestate"] = tc.TraceState
+	}
+	return fields
+}
+
+// TraceParent - the traceparent header value for the trace context, with the server's span-id as the parent-id (or the
+// caller's parent-id when the server doesn't have one)
+func (tc TraceContext) TraceParent() string {
+	parentID := tc.SpanID
+	if len(parentID) == 0 {
+		parentID = tc.ParentID
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, parentID, tc.Flags)
+}
+
+// TraceResponse - the traceresponse header value for the trace context, with the server's span-id as the child-id
+func (tc TraceContext) TraceResponse(childID string) string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, childID, tc.Flags&0x01)
+}
+
+// newSpanID - generate a random 8 byte span-id
+func newSpanID() string {
+	b := make([]byte, 8)
+	for {
+		if _, err := rand.Read(b); err == nil && strings.Trim(hex.EncodeToString(b), "0") != "" {
+			return hex.EncodeToString(b)
+		}
+	}
+}
+
+// isLowerHex - is s all lowercase hex characters
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
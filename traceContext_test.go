@@ -0,0 +1,85 @@
+package ginlogrus
+
+import (
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name         string
+		traceParent  string
+		wantTraceID  string
+		wantParentID string
+		wantSampled  bool
+		wantErr      bool
+	}{
+		{name: "sampled", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", wantParentID: "00f067aa0ba902b7", wantSampled: true},
+		{name: "not-sampled", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", wantParentID: "00f067aa0ba902b7", wantSampled: false},
+		{name: "future-version", traceParent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-whatever", wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", wantParentID: "00f067aa0ba902b7", wantSampled: true},
+		{name: "empty", traceParent: "", wantErr: true},
+		{name: "version-ff", traceParent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "version-00-too-long", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", wantErr: true},
+		{name: "uppercase", traceParent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "zero-trace-id", traceParent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", wantErr: true},
+		{name: "zero-span-id", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", wantErr: true},
+		{name: "bad-delimiter", traceParent: "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", wantErr: true},
+		{name: "bad-flags", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceParent(tt.traceParent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceParent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.TraceID != tt.wantTraceID || got.ParentID != tt.wantParentID || got.Sampled() != tt.wantSampled {
+				t.Errorf("ParseTraceParent() = %+v, want %v %v %v", got, tt.wantTraceID, tt.wantParentID, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestCxtTraceContext(t *testing.T) {
+	c := getTestContext(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false)
+	c.Request.Header.Add(TraceStateHeader, "rojo=00f067aa0ba902b7")
+	c.Request.Header.Add(TraceStateHeader, "congo=t61rcWkgMzE")
+	tc, ok := CxtTraceContext(c)
+	if !ok {
+		t.Fatal("CxtTraceContext() didn't find the trace context")
+	}
+	if tc.TraceState != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Errorf("CxtTraceContext() TraceState = %v", tc.TraceState)
+	}
+	if got := tc.TraceResponse("b7ad6b7169203331"); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01" {
+		t.Errorf("TraceContext.TraceResponse() = %v", got)
+	}
+	if got := tc.TraceParent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("TraceContext.TraceParent() = %v", got)
+	}
+	if got := CxtRequestID(c); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("CxtRequestID() = %v, want the trace-id", got)
+	}
+	fields := GetCtxLogger(c).Data
+	if fields["trace-id"] != tc.TraceID || fields["parent-id"] != tc.ParentID || fields["trace-sampled"] != true {
+		t.Errorf("GetCtxLogger() fields = %v", fields)
+	}
+	if _, found := fields["span-id"]; found {
+		t.Errorf("GetCtxLogger() fields = %v, want no span-id without a server span", fields)
+	}
+	// with the server's span, it's the parent-id of the outbound requests
+	tc.SpanID = "b7ad6b7169203331"
+	if got := tc.TraceParent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01" {
+		t.Errorf("TraceContext.TraceParent() = %v", got)
+	}
+	if tc.Fields()["span-id"] != tc.SpanID {
+		t.Errorf("TraceContext.Fields() = %v", tc.Fields())
+	}
+
+	c = getTestContext(TraceParentHeader, "not-a-traceparent", false)
+	c.Request.Header.Add(TraceStateHeader, "rojo=00f067aa0ba902b7")
+	if _, ok := CxtTraceContext(c); ok {
+		t.Error("CxtTraceContext() shouldn't find an invalid trace context")
+	}
+}